@@ -0,0 +1,104 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// AuditConfig names a value of a YAML or JSON config file, such as the
+// kubelet config file, for a check to audit instead of running a command.
+//
+//   audit_config:
+//     file: /var/lib/kubelet/config.yaml
+//     key: authorization.mode
+//
+// Key is a dotted path into the file, where numbers index lists. The tests
+// of the check are run on "key=value", so that the key is tested like a
+// flag. Lists are given as comma-separated values, and keys holding maps
+// are given without a value. A key that is not in the file is not set.
+type AuditConfig struct {
+	File string `yaml:"file"`
+	Key  string `yaml:"key"`
+}
+
+// read returns the value of the key in the config file, formatted for the
+// tests of a check, or "" if the file does not have the key.
+func (a *AuditConfig) read() (string, error) {
+	in, err := ioutil.ReadFile(a.File)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("audit config file %s not found", a.File)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read audit config file %s: %v", a.File, err)
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(in, &doc); err != nil {
+		return "", fmt.Errorf("failed to parse audit config file %s: %v", a.File, err)
+	}
+
+	v, ok := lookupKey(doc, a.Key)
+	if !ok || v == nil {
+		return "", nil
+	}
+
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		return a.Key, nil
+	case []interface{}:
+		vals := make([]string, len(v))
+		for i, e := range v {
+			vals[i] = fmt.Sprint(e)
+		}
+		return a.Key + "=" + strings.Join(vals, ","), nil
+	}
+	return a.Key + "=" + fmt.Sprint(v), nil
+}
+
+// lookupKey returns the value at the dotted path key of doc, as parsed by
+// yaml.Unmarshal, and whether it was found.
+func lookupKey(doc interface{}, key string) (interface{}, bool) {
+	if key == "" {
+		return doc, true
+	}
+
+	v := doc
+	for _, k := range strings.Split(key, ".") {
+		switch node := v.(type) {
+		case map[interface{}]interface{}:
+			e, ok := node[k]
+			if !ok {
+				return nil, false
+			}
+			v = e
+		case []interface{}:
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
@@ -0,0 +1,83 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck_RunAuditConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	config := `
+authorization:
+  mode: Webhook
+authentication:
+  anonymous:
+    enabled: false
+tlsCipherSuites: ["TLS_AES_128_GCM_SHA256", "TLS_AES_256_GCM_SHA384"]
+`
+	if err := ioutil.WriteFile(file, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		key   string
+		item  testItem
+		state State
+		out   string
+	}{
+		{"authorization.mode", testItem{Flag: "authorization.mode", Set: true, Compare: compare{Op: "eq", Value: "Webhook"}}, PASS, "authorization.mode=Webhook"},
+		{"authentication.anonymous.enabled", testItem{Flag: "authentication.anonymous.enabled", Set: true, Compare: compare{Op: "eq", Value: "true"}}, FAIL, "authentication.anonymous.enabled=false"},
+		{"tlsCipherSuites", testItem{Flag: "tlsCipherSuites", Set: true, Compare: compare{Op: "has", Value: "TLS_AES_256_GCM_SHA384"}}, PASS, "tlsCipherSuites=TLS_AES_128_GCM_SHA256,TLS_AES_256_GCM_SHA384"},
+		{"tlsCipherSuites.0", testItem{Flag: "tlsCipherSuites.0", Set: true, Compare: compare{Op: "eq", Value: "TLS_AES_128_GCM_SHA256"}}, PASS, "tlsCipherSuites.0=TLS_AES_128_GCM_SHA256"},
+		{"authentication", testItem{Flag: "authentication", Set: true}, PASS, "authentication"},
+		{"readOnlyPort", testItem{Flag: "readOnlyPort", Set: false}, PASS, ""},
+	}
+
+	for _, c := range cases {
+		item := c.item
+		check := &Check{
+			ID:          "4.2.1",
+			Scored:      true,
+			AuditConfig: &AuditConfig{File: file, Key: c.key},
+			Tests:       &tests{TestItems: []*testItem{&item}},
+		}
+		check.Run()
+		if check.State != c.state || check.AuditOutput != c.out {
+			t.Errorf("%s: expected %s with output %q, got %s with output %q", c.key, c.state, c.out, check.State, check.AuditOutput)
+		}
+	}
+
+	check := &Check{
+		ID:          "4.2.2",
+		Scored:      true,
+		AuditConfig: &AuditConfig{File: filepath.Join(dir, "missing.yaml"), Key: "authorization.mode"},
+		Tests:       &tests{TestItems: []*testItem{{Flag: "authorization.mode", Set: false}}},
+	}
+	check.Run()
+	if check.State != ERROR || len(check.TestInfo) != 1 || !strings.Contains(check.TestInfo[0], "missing.yaml not found") {
+		t.Errorf("expected a missing config file to error with a reason, got %s %q", check.State, check.TestInfo)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import "fmt"
+
+// ApplyBaseline returns a copy of the controls in which the findings
+// accepted by baseline, which maps check IDs to the state they are accepted
+// in, are downgraded to INFO, so that only new findings count as failures.
+// A FAIL or WARN check is baselined only if baseline holds the same state
+// for its ID: a check that has gone from WARN to FAIL is still reported.
+// Baselined checks note their original state in TestInfo, and summaries are
+// recomputed from the resulting states. The receiver is not modified.
+func (controls *Controls) ApplyBaseline(baseline map[string]State) *Controls {
+	c := controls.Clone()
+	c.EachCheck(func(group *Group, check *Check) {
+		if check.State != FAIL && check.State != WARN {
+			return
+		}
+		if s, ok := baseline[check.ID]; !ok || s != check.State {
+			return
+		}
+		check.TestInfo = append(check.TestInfo, fmt.Sprintf("Baselined: accepted as %s", check.State))
+		check.State = INFO
+	})
+	c.resummarize()
+	return c
+}
+
+// resummarize recomputes the summaries of the controls and of each group of
+// the last run from the states of their checks, the counts of a group
+// including its subgroups.
+func (controls *Controls) resummarize() {
+	controls.Summary = Summary{}
+	controls.SummaryLevelWise = map[string]*Summary{}
+
+	for _, group := range flattenGroups(controls.Groups) {
+		resetGroup(group)
+		for _, g := range flattenGroups([]*Group{group}) {
+			for _, check := range g.Checks {
+				summarizeGroup(group, check)
+			}
+		}
+		for _, check := range group.Checks {
+			summarize(controls, check)
+			summarizeLevel(controls, check)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestControls_ApplyBaseline(t *testing.T) {
+	in := []byte(`---
+controls:
+id: 1
+text: "Master Node Security Configuration"
+type: "master"
+groups:
+- id: 1.1
+  text: "API Server"
+  checks:
+  - id: 1.1.1
+    text: "accepted failure"
+    audit: "echo --anonymous-auth=true"
+    tests:
+      test_items:
+      - flag: "--anonymous-auth"
+        compare:
+          op: eq
+          value: false
+        set: true
+    scored: true
+    level: 1
+  - id: 1.1.2
+    text: "new failure"
+    audit: "echo --profiling=true"
+    tests:
+      test_items:
+      - flag: "--profiling"
+        compare:
+          op: eq
+          value: false
+        set: true
+    scored: true
+    level: 1
+  - id: 1.1.3
+    text: "accepted as WARN, now a manual check"
+    type: "manual"
+    level: 1
+  - id: 1.1.4
+    text: "warning accepted as FAIL"
+    type: "manual"
+    level: 1
+`)
+	controls, err := NewControls(MASTER, "1", in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := controls.RunGroup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c := controls.ApplyBaseline(map[string]State{"1.1.1": FAIL, "1.1.3": WARN, "1.1.4": FAIL})
+
+	expected := map[string]State{"1.1.1": INFO, "1.1.2": FAIL, "1.1.3": INFO, "1.1.4": WARN}
+	for id, state := range expected {
+		check, _ := c.FindCheck(id)
+		if check.State != state {
+			t.Errorf("check %s: expected %s, got %s", id, state, check.State)
+		}
+	}
+	if check, _ := c.FindCheck("1.1.1"); !strings.Contains(strings.Join(check.TestInfo, "\n"), "Baselined: accepted as FAIL") {
+		t.Errorf("expected baselined check to note its state, got %v", check.TestInfo)
+	}
+
+	s := Summary{Fail: 1, Warn: 1, Info: 2}
+	if c.Summary != s || *c.SummaryLevelWise["1"] != s {
+		t.Errorf("expected summary %v, got %v and level-wise %v", s, c.Summary, *c.SummaryLevelWise["1"])
+	}
+	if g := c.Groups[0]; g.Fail != 1 || g.Warn != 1 || g.Info != 2 {
+		t.Errorf("unexpected group counts: %+v", g)
+	}
+
+	if controls.Summary != (Summary{Fail: 2, Warn: 2}) {
+		t.Errorf("receiver was modified: %v", controls.Summary)
+	}
+}
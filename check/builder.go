@@ -0,0 +1,111 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import "fmt"
+
+// DefaultLevel is the CIS level that built controls are run at unless
+// Level sets another, as for the --level flag of the command line.
+const DefaultLevel = "2"
+
+// ControlsBuilder builds Controls in code rather than from a controls
+// file, for example in tests or to run checks generated from another
+// source:
+//
+//	controls, err := NewControlsBuilder(MASTER).
+//		Level("1").
+//		AddGroup("1.1", "API Server").
+//		AddCheck(&Check{ID: "1.1.1", Text: "Ensure ...", Audit: "ps -ef", CheckCISLevel: "1"}).
+//		Build()
+//
+// Checks are added to the group added last.
+type ControlsBuilder struct {
+	controls *Controls
+	group    *Group
+	err      error
+}
+
+// NewControlsBuilder returns a builder of controls of node type t.
+func NewControlsBuilder(t NodeType) *ControlsBuilder {
+	return &ControlsBuilder{controls: &Controls{Type: t, UserCISLevel: DefaultLevel}}
+}
+
+// ID sets the ID and description of the controls.
+func (b *ControlsBuilder) ID(id, text string) *ControlsBuilder {
+	b.controls.ID, b.controls.Text = id, text
+	return b
+}
+
+// Version sets the benchmark version of the controls.
+func (b *ControlsBuilder) Version(version string) *ControlsBuilder {
+	b.controls.Version = version
+	return b
+}
+
+// Level sets the CIS level the controls are run at, as the level argument
+// of NewControls, rather than DefaultLevel.
+func (b *ControlsBuilder) Level(level string) *ControlsBuilder {
+	b.controls.UserCISLevel = level
+	return b
+}
+
+// AddGroup adds a group to the controls, to which the checks added next
+// belong.
+func (b *ControlsBuilder) AddGroup(id, text string) *ControlsBuilder {
+	b.group = &Group{ID: id, Text: text}
+	b.controls.Groups = append(b.controls.Groups, b.group)
+	return b
+}
+
+// AddCheck adds checks to the group added last. Adding checks before any
+// group is an error reported by Build.
+func (b *ControlsBuilder) AddCheck(checks ...*Check) *ControlsBuilder {
+	if b.group == nil {
+		if b.err == nil && len(checks) > 0 {
+			b.err = fmt.Errorf("check %q added before any group", checks[0].ID)
+		}
+		return b
+	}
+	b.group.Checks = append(b.group.Checks, checks...)
+	return b
+}
+
+// Build validates the controls built as NewControls validates controls
+// files, and prepares the audit commands of their checks. As with
+// NewControls, checks without a level are given one from their profile or
+// scored fields. The controls returned hold the groups and checks added, so
+// the builder should not be used once they are built.
+func (b *ControlsBuilder) Build() (*Controls, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	c := b.controls
+	for _, group := range flattenGroups(c.Groups) {
+		for _, check := range group.Checks {
+			check.deriveLevel()
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	for _, group := range flattenGroups(c.Groups) {
+		for _, check := range group.Checks {
+			check.Commands = check.prepareCommands()
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,138 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestControlsBuilder(t *testing.T) {
+	built, err := NewControlsBuilder(MASTER).
+		ID("1", "Master Node Security Configuration").
+		Version("1.13").
+		Level("1").
+		AddGroup("1.1", "API Server").
+		AddCheck(
+			&Check{ID: "1.1.1", Text: "first check", Audit: "echo apiserver | grep apiserver", CheckCISLevel: "1", Scored: true},
+			&Check{ID: "1.1.2", Text: "second check", Type: "manual", CheckCISLevel: "1"},
+		).
+		AddGroup("1.2", "Scheduler").
+		AddCheck(&Check{ID: "1.2.1", Text: "third check", Pipeline: [][]string{{"echo", "x"}}, CheckCISLevel: "1"}).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := NewControls(MASTER, "1", []byte(`---
+controls:
+id: 1
+text: "Master Node Security Configuration"
+type: "master"
+version: "1.13"
+groups:
+- id: 1.1
+  text: "API Server"
+  checks:
+  - id: 1.1.1
+    text: "first check"
+    audit: "echo apiserver | grep apiserver"
+    level: 1
+    scored: true
+  - id: 1.1.2
+    text: "second check"
+    type: "manual"
+    level: 1
+- id: 1.2
+  text: "Scheduler"
+  checks:
+  - id: 1.2.1
+    text: "third check"
+    level: 1
+    pipeline:
+    - ["echo", "x"]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(built.Groups[0].Checks[0].AuditCommands(), parsed.Groups[0].Checks[0].AuditCommands()) {
+		t.Errorf("expected commands %q, got %q", parsed.Groups[0].Checks[0].AuditCommands(), built.Groups[0].Checks[0].AuditCommands())
+	}
+	if len(built.Groups[1].Checks[0].Commands) != 1 {
+		t.Errorf("expected the pipeline to be prepared, got %d commands", len(built.Groups[1].Checks[0].Commands))
+	}
+
+	bs, err := built.RunGroup()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ps, err := parsed.RunGroup()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bs != ps {
+		t.Errorf("expected the built controls to give %v as parsed ones, got %v", ps, bs)
+	}
+	if built.ID != parsed.ID || built.Text != parsed.Text || built.Version != parsed.Version || built.Type != parsed.Type {
+		t.Errorf("expected %s %q %s %s, got %s %q %s %s", parsed.ID, parsed.Text, parsed.Version, parsed.Type, built.ID, built.Text, built.Version, built.Type)
+	}
+}
+
+func TestControlsBuilder_Invalid(t *testing.T) {
+	_, err := NewControlsBuilder(NODE).
+		AddGroup("2.1", "Kubelet").
+		AddCheck(&Check{ID: "2.1.1"}, &Check{ID: "2.1.1", CheckCISLevel: "one"}).
+		Build()
+	if err == nil || !strings.Contains(err.Error(), "duplicate ids: check 2.1.1") || !strings.Contains(err.Error(), `invalid level "one"`) {
+		t.Errorf("expected the builder to validate the controls, got %v", err)
+	}
+
+	_, err = NewControlsBuilder(NODE).
+		AddCheck(&Check{ID: "2.1.1"}).
+		AddGroup("2.1", "Kubelet").
+		Build()
+	if err == nil || err.Error() != `check "2.1.1" added before any group` {
+		t.Errorf("expected a check without a group to be an error, got %v", err)
+	}
+}
+
+func TestControlsBuilder_Defaults(t *testing.T) {
+	c, err := NewControlsBuilder(NODE).
+		AddGroup("2.1", "Kubelet").
+		AddCheck(
+			&Check{ID: "2.1.1", Pipeline: [][]string{{"echo", "x"}}, Scored: true, Tests: &tests{TestItems: []*testItem{{Flag: "x", Set: true}}}},
+			&Check{ID: "2.1.2", Type: "manual", Profile: "Level 2 - Worker Node"},
+		).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.UserCISLevel != DefaultLevel {
+		t.Errorf("expected level %s, got %s", DefaultLevel, c.UserCISLevel)
+	}
+	if l1, l2 := c.Groups[0].Checks[0].CheckCISLevel, c.Groups[0].Checks[1].CheckCISLevel; l1 != "1" || l2 != "2" {
+		t.Errorf("expected levels to be derived as by NewControls, got %s and %s", l1, l2)
+	}
+
+	summary, err := c.RunGroup()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary != (Summary{Pass: 1, Warn: 1}) {
+		t.Errorf("expected both checks to run, got %v", summary)
+	}
+}
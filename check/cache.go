@@ -0,0 +1,113 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ResultCache holds the results of checks, keyed by check ID, so that a
+// check run again within the TTL of the cache reuses its last result rather
+// than running its audit commands, for example when the same controls are
+// run in a tight loop. Only PASS and FAIL results are kept, as the other
+// states either need no audit or, like a timeout, may not last. A cache is
+// meant for the checks of one controls file and is safe for concurrent use.
+type ResultCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	results map[string]cachedResult
+
+	// now returns the current time, and is replaced by tests.
+	now func() time.Time
+}
+
+type cachedResult struct {
+	state         State
+	actualValue   string
+	expectedValue string
+	passedTests   int
+	totalTests    int
+	auditOutput   string
+	testInfo      []string
+	timestamp     time.Time
+}
+
+// NewResultCache returns an empty cache whose results are reused for ttl
+// after they are measured.
+func NewResultCache(ttl time.Duration) *ResultCache {
+	return &ResultCache{
+		ttl:     ttl,
+		results: make(map[string]cachedResult),
+		now:     time.Now,
+	}
+}
+
+// Clear removes every result from the cache, so that the checks are run
+// again.
+func (rc *ResultCache) Clear() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.results = make(map[string]cachedResult)
+}
+
+// restore sets the result of check to the one cached for its ID, noting in
+// its TestInfo when it was measured, and reports whether a result was
+// cached within the TTL.
+func (rc *ResultCache) restore(check *Check) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	r, ok := rc.results[check.ID]
+	if !ok {
+		return false
+	}
+	if rc.now().Sub(r.timestamp) >= rc.ttl {
+		delete(rc.results, check.ID)
+		return false
+	}
+
+	check.State = r.state
+	check.ActualValue = r.actualValue
+	check.ExpectedValue = r.expectedValue
+	check.PassedTests, check.TotalTests = r.passedTests, r.totalTests
+	check.AuditOutput = r.auditOutput
+	check.TestInfo = append(check.TestInfo, r.testInfo...)
+	check.TestInfo = append(check.TestInfo, fmt.Sprintf("Cached result from %s", r.timestamp.UTC().Format(time.RFC3339)))
+	check.Timestamp = r.timestamp
+	return true
+}
+
+// store keeps the result of check if it passed or failed.
+func (rc *ResultCache) store(check *Check) {
+	if check.State != PASS && check.State != FAIL {
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.results[check.ID] = cachedResult{
+		state:         check.State,
+		actualValue:   check.ActualValue,
+		expectedValue: check.ExpectedValue,
+		passedTests:   check.PassedTests,
+		totalTests:    check.TotalTests,
+		auditOutput:   check.AuditOutput,
+		testInfo:      cloneStrings(check.TestInfo),
+		timestamp:     check.Timestamp,
+	}
+}
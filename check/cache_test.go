@@ -0,0 +1,104 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestControls_ResultCache(t *testing.T) {
+	cache := NewResultCache(time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	c := &Controls{
+		UserCISLevel: "1",
+		ResultCache:  cache,
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{
+				{ID: "1.1.1", Audit: "echo --anonymous-auth=false", Scored: true, CheckCISLevel: "1", Tests: &tests{TestItems: []*testItem{{
+					Flag:    "--anonymous-auth",
+					Set:     true,
+					Compare: compare{Op: "eq", Value: "false"},
+				}}}},
+				{ID: "1.1.2", Type: "manual", CheckCISLevel: "1"},
+			}},
+		},
+	}
+	for _, check := range c.Groups[0].Checks {
+		check.Commands = check.prepareCommands()
+	}
+
+	cached := func(check *Check) bool {
+		for _, info := range check.TestInfo {
+			if strings.HasPrefix(info, "Cached result from ") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if _, err := c.RunGroup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	passed := c.Groups[0].Checks[0]
+	measured := passed.Timestamp
+	if passed.State != PASS || cached(passed) {
+		t.Fatalf("expected the check to run and pass, got %s %q", passed.State, passed.TestInfo)
+	}
+
+	// Change the audit so that running the check again would fail.
+	passed.Audit = "echo --anonymous-auth=true"
+	passed.Commands = passed.prepareCommands()
+
+	now = now.Add(30 * time.Second)
+	summary, err := c.RunGroup()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if passed.State != PASS || !cached(passed) || !passed.Timestamp.Equal(measured) {
+		t.Errorf("expected the cached result, got %s %q at %v", passed.State, passed.TestInfo, passed.Timestamp)
+	}
+	if passed.AuditOutput != "--anonymous-auth=false\n" {
+		t.Errorf("expected the cached audit output, got %q", passed.AuditOutput)
+	}
+	if manual := c.Groups[0].Checks[1]; cached(manual) {
+		t.Errorf("expected WARN results not to be cached, got %q", manual.TestInfo)
+	}
+	if summary != (Summary{Pass: 1, Warn: 1}) {
+		t.Errorf("unexpected summary: %v", summary)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := c.RunGroup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if passed.State != FAIL || cached(passed) {
+		t.Errorf("expected the expired result to be measured again, got %s %q", passed.State, passed.TestInfo)
+	}
+
+	cache.Clear()
+	c.ResultCache = nil
+	passed.Audit = "echo --anonymous-auth=false"
+	passed.Commands = passed.prepareCommands()
+	if _, err := c.RunGroup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if passed.State != PASS || cached(passed) {
+		t.Errorf("expected the check to run without a cache, got %s %q", passed.State, passed.TestInfo)
+	}
+}
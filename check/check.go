@@ -16,14 +16,17 @@ package check
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strings"
-
-	"github.com/golang/glog"
+	"sync/atomic"
+	"time"
 )
 
 // NodeType indicates the type of node (master, node, federated).
@@ -43,6 +46,10 @@ const (
 	INFO = "INFO"
 	// SKIP for tests skipped
 	SKIP = "SKIP"
+	// NA for tests not applicable to the environment
+	NA = "NA"
+	// ERROR could not run the audit of the check, so its result is unknown.
+	ERROR = "ERROR"
 
 	// MASTER a master node
 	MASTER NodeType = "master"
@@ -50,8 +57,59 @@ const (
 	NODE NodeType = "node"
 	// FEDERATED a federated deployment.
 	FEDERATED NodeType = "federated"
+	// ETCD an etcd node
+	ETCD NodeType = "etcd"
+	// CONTROLPLANE the control plane configuration
+	CONTROLPLANE NodeType = "controlplane"
+	// POLICIES the cluster policies
+	POLICIES NodeType = "policies"
+)
+
+// Severities of checks, from least to most severe.
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
 )
 
+// DefaultSeverity is the severity of checks that have none, when no other
+// default is configured.
+const DefaultSeverity = SeverityMedium
+
+// Severities returns the known severities, from least to most severe.
+func Severities() []string {
+	return []string{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+}
+
+func isSeverity(s string) bool {
+	for _, severity := range Severities() {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeTypes returns all of the known node types.
+func NodeTypes() []NodeType {
+	return []NodeType{MASTER, NODE, FEDERATED, ETCD, CONTROLPLANE, POLICIES}
+}
+
+// ParseNodeType returns the known node type named s.
+func ParseNodeType(s string) (NodeType, error) {
+	for _, t := range NodeTypes() {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown node type %q", s)
+}
+
+func (n NodeType) String() string {
+	return string(n)
+}
+
 func handleError(err error, context string) (errmsg string) {
 	if err != nil {
 		errmsg = fmt.Sprintf("%s, error: %s\n", context, err)
@@ -63,23 +121,315 @@ func handleError(err error, context string) (errmsg string) {
 // CIS Kubernetes 1.6+ document.
 type Check struct {
 	ID          	string      `yaml:"id" json:"test_number"`
-	Text        	string      `json:"test_desc"`
-	Audit       	string      `json:"omit"`
-	Type        	string      `json:"type"`
-	Commands    	[]*exec.Cmd `json:"omit"`
-	Tests       	*tests      `json:"omit"`
-	Set         	bool        `json:"omit"`
-	Remediation 	string      `json:"-"`
-	TestInfo    	[]string    `json:"test_info"`
+	Text        	string      `yaml:"text" json:"test_desc"`
+	Audit       	string      `yaml:"audit" json:"omit"`
+	Pipeline    	[][]string	`yaml:"pipeline" json:"-"`
+	AuditConfig 	*AuditConfig	`yaml:"audit_config" json:"-"`
+	Type        	string      `yaml:"type" json:"type"`
+	Commands    	[]*exec.Cmd `yaml:"-" json:"omit"`
+	Tests       	*tests      `yaml:"tests" json:"omit"`
+	Set         	bool        `yaml:"set" json:"omit"`
+	Remediation 	string      `yaml:"remediation" json:"-"`
+	Remediations	map[string]string	`yaml:"remediations" json:"-"`
+	TestInfo    	[]string    `yaml:"test_info" json:"test_info"`
 	CheckCISLevel	string		`yaml:"level" json:"level"`
-	State       				`json:"status"`
-	ActualValue 	string 		`json:"actual_value"`
-	Scored      	bool   		`json:"scored"`
+	Profile     	string		`yaml:"profile" json:"profile,omitempty"`
+	State       				`yaml:"status" json:"status"`
+	ActualValue 	string 		`yaml:"actual_value" json:"actual_value"`
+	ExpectedValue	string		`yaml:"-" json:"expected_value,omitempty"`
+	PassedTests 	int 		`yaml:"-" json:"passed_tests,omitempty"`
+	TotalTests  	int 		`yaml:"-" json:"total_tests,omitempty"`
+	AuditOutput 	string 		`yaml:"-" json:"audit_output,omitempty"`
+	Scored      	bool   		`yaml:"scored" json:"scored"`
+	Critical    	bool   		`yaml:"critical" json:"critical"`
+	Negate      	bool   		`yaml:"negate" json:"negate,omitempty"`
+	Severity    	string 		`yaml:"severity" json:"severity,omitempty"`
+	SkipReason  	string 		`yaml:"-" json:"skip_reason,omitempty"`
+	Annotations 	map[string]string	`yaml:"annotations" json:"annotations,omitempty"`
+	DependsOn   	[]string	`yaml:"depends_on" json:"depends_on,omitempty"`
+	Tags        	[]string	`yaml:"tags" json:"tags,omitempty"`
+	MinVersion  	string		`yaml:"min_version" json:"min_version,omitempty"`
+	MaxVersion  	string		`yaml:"max_version" json:"max_version,omitempty"`
+	Platforms   	[]string	`yaml:"platforms" json:"platforms,omitempty"`
+	Duration    	time.Duration	`yaml:"-" json:"-"`
+	DurationMS  	int64  		`yaml:"-" json:"duration_ms"`
+
+	// Timestamp is when the check last finished running, that is when its
+	// result was captured.
+	Timestamp time.Time `yaml:"-" json:"timestamp"`
+
+	// nodeType is the type of the controls that ran the check, for Event.
+	nodeType NodeType
+
+	// defaultSeverity records that Severity was set to the default by the
+	// run rather than by the controls file.
+	defaultSeverity bool
+
+	// runCritical records that Critical was set by the CriticalChecks of
+	// the run rather than by the controls file.
+	runCritical bool
+
+	// untranslated holds the text and remediation of the controls file
+	// once Translate has replaced them.
+	untranslated *checkText
+}
+
+type checkText struct {
+	text        string
+	remediation string
+}
+
+// DefaultTimeout is how long the audit commands of a check may run before
+// they are stopped, when no other timeout is configured.
+const DefaultTimeout = 30 * time.Second
+
+// DefaultShell is the shell used to look up audit commands when no other
+// shell is configured.
+const DefaultShell = "/bin/sh"
+
+// DefaultAuditOutputLimit is how many bytes of the output of the audit
+// commands are kept in AuditOutput when no other limit is configured.
+const DefaultAuditOutputLimit = 4096
+
+// runOptions configures how a check runs.
+type runOptions struct {
+	// timeout bounds how long the audit commands may run.
+	timeout time.Duration
+	// shell is used to look up the audit commands.
+	shell string
+	// outputLimit is how many bytes of output are kept, if positive.
+	outputLimit int
+	// retries is how many more times a failed check is run.
+	retries int
+	// backoff is how long to wait before the first retry, doubling for
+	// each further retry.
+	backoff time.Duration
+	// cache, if set, holds the results reused rather than running the
+	// check again.
+	cache *ResultCache
+	// logger, if set, receives the diagnostics of the check rather than
+	// the logger set with SetLogger.
+	logger Logger
+}
+
+func (opts runOptions) log() Logger {
+	if opts.logger != nil {
+		return opts.logger
+	}
+	return currentLogger()
+}
+
+var defaultRunOptions = runOptions{
+	timeout:     DefaultTimeout,
+	shell:       DefaultShell,
+	outputLimit: DefaultAuditOutputLimit,
+}
+
+// RemediationFor returns the remediation of the check for a distribution,
+// such as "kubeadm": Remediation followed by the entry of Remediations for
+// distro. When distro is empty or has no entry, every entry is included,
+// prefixed with its distribution.
+func (c *Check) RemediationFor(distro string) string {
+	return strings.Join(c.remediations(distro), "\n")
+}
+
+func (c *Check) remediations(distro string) []string {
+	if len(c.Remediations) == 0 {
+		return []string{c.Remediation}
+	}
+
+	r := []string{}
+	if c.Remediation != "" {
+		r = append(r, c.Remediation)
+	}
+
+	if s, ok := c.Remediations[distro]; ok && distro != "" {
+		return append(r, s)
+	}
+
+	distros := []string{}
+	for d := range c.Remediations {
+		distros = append(distros, d)
+	}
+	sort.Strings(distros)
+	for _, d := range distros {
+		r = append(r, d+": "+c.Remediations[d])
+	}
+	return r
+}
+
+// skip marks the check SKIP for reason.
+func (c *Check) skip(reason string) {
+	c.State = SKIP
+	c.SkipReason = reason
+	c.TestInfo = append(c.TestInfo, "Skipped: "+reason)
+}
+
+// AuditCommands returns the commands that are run for the check, in
+// pipeline order, each with its arguments separated by spaces.
+func (c *Check) AuditCommands() []string {
+	cmds := []string{}
+	for _, cmd := range c.Commands {
+		cmds = append(cmds, strings.Join(cmd.Args, " "))
+	}
+	return cmds
+}
+
+// reset clears the results of a previous run so that the check can be run
+// again. Audit commands that were started are prepared again, as a command
+// can only be started once.
+func (c *Check) reset() {
+	c.State = ""
+	c.SkipReason = ""
+	c.TestInfo = nil
+	c.ActualValue = ""
+	c.ExpectedValue = ""
+	c.PassedTests = 0
+	c.TotalTests = 0
+	c.AuditOutput = ""
+	c.Duration = 0
+	c.DurationMS = 0
+	c.Timestamp = time.Time{}
+	if c.defaultSeverity {
+		c.Severity = ""
+		c.defaultSeverity = false
+	}
+	if c.runCritical {
+		c.Critical = false
+		c.runCritical = false
+	}
+
+	for _, cmd := range c.Commands {
+		if cmd.Process != nil {
+			c.Commands = c.prepareCommands()
+			break
+		}
+	}
+}
+
+// clone returns a copy of the check that shares nothing with it. The audit
+// commands are prepared again rather than copied, as a command holds the
+// state of its process and can only be started once.
+func (c *Check) clone() *Check {
+	cc := *c
+	cc.Commands = c.prepareCommands()
+	cc.Tests = c.Tests.clone()
+	if c.AuditConfig != nil {
+		ac := *c.AuditConfig
+		cc.AuditConfig = &ac
+	}
+	if c.Pipeline != nil {
+		cc.Pipeline = make([][]string, len(c.Pipeline))
+		for i, stage := range c.Pipeline {
+			cc.Pipeline[i] = cloneStrings(stage)
+		}
+	}
+	cc.TestInfo = cloneStrings(c.TestInfo)
+	cc.DependsOn = cloneStrings(c.DependsOn)
+	cc.Tags = cloneStrings(c.Tags)
+	cc.Platforms = cloneStrings(c.Platforms)
+	cc.Remediations = cloneStringMap(c.Remediations)
+	cc.Annotations = cloneStringMap(c.Annotations)
+	return &cc
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
+
+func cloneStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
 }
 
+// deriveLevel sets the level of a check that has none from the fields
+// legacy controls files use instead. The level key wins if present, then
+// the first number of profile, such as 2 for "Level 2 - Master Node", then
+// scored: scored checks are level 1 and the others level 2.
+func (c *Check) deriveLevel() {
+	if c.CheckCISLevel != "" {
+		return
+	}
+	if level := profileLevel.FindString(c.Profile); level != "" {
+		c.CheckCISLevel = level
+		return
+	}
+	if c.Scored {
+		c.CheckCISLevel = "1"
+	} else {
+		c.CheckCISLevel = "2"
+	}
+}
+
+var profileLevel = regexp.MustCompile(`[0-9]+`)
+
 // Run executes the audit commands specified in a check and outputs
 // the results.
 func (c *Check) Run() {
+	c.run(context.Background(), defaultRunOptions)
+}
+
+// run executes the check, running it again up to opts.retries times while
+// it fails in case the failure was transient. A result fresh in opts.cache
+// is used instead of running the check.
+func (c *Check) run(ctx context.Context, opts runOptions) {
+	if opts.cache != nil && c.State != SKIP {
+		if opts.cache.restore(c) {
+			return
+		}
+		defer opts.cache.store(c)
+	}
+
+	start := time.Now()
+	defer func() {
+		c.Timestamp = time.Now()
+		c.Duration = c.Timestamp.Sub(start)
+		c.DurationMS = int64(c.Duration / time.Millisecond)
+	}()
+
+	info := c.TestInfo
+	backoff := opts.backoff
+	for retry := 0; ; retry++ {
+		c.runOnce(ctx, opts)
+		if c.State != FAIL {
+			if retry > 0 && c.State == PASS {
+				c.TestInfo = append(c.TestInfo, fmt.Sprintf("Succeeded after %d retries", retry))
+			}
+			return
+		}
+		if retry == opts.retries {
+			return
+		}
+
+		opts.log().Debug("retrying failed check", "check", c.ID, "backoff", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff *= 2
+
+		c.TestInfo = info
+		c.Commands = c.prepareCommands()
+	}
+}
+
+// runOnce executes the audit commands of the check, stopping them and
+// marking the check WARN if they have not completed within opts.timeout or
+// before ctx is done. opts.shell is used to look up the commands. The check
+// is marked ERROR, with the reason in TestInfo, if its audit cannot be run
+// at all, such as when the shell or a command cannot be found.
+func (c *Check) runOnce(ctx context.Context, opts runOptions) {
+	timeout, shell := opts.timeout, opts.shell
 
 	// If check State is SKIP then return
 	// State of check is SKIP when user
@@ -100,15 +450,63 @@ func (c *Check) Run() {
 		return
 	}
 
+	if c.AuditConfig != nil {
+		out, err := c.AuditConfig.read()
+		if err != nil {
+			c.State = ERROR
+			c.TestInfo = append(c.TestInfo, err.Error())
+			opts.log().Warn("audit config could not be read", "check", c.ID, "error", err)
+			return
+		}
+		if msg := c.evaluate(out, opts); msg != "" {
+			opts.log().Error("tests could not be run", "check", c.ID, "error", msg)
+		}
+		return
+	}
+
 	var out bytes.Buffer
 	var errmsgs string
 
-	// Check if command exists or exit with WARN.
-	for _, cmd := range c.Commands {
-		if !isShellCommand(cmd.Path) {
-			c.State = WARN
+	audit := c.Audit
+	if len(c.Pipeline) > 0 {
+		// Pipelines are run without a shell, so their programs are looked
+		// up in PATH rather than by the shell.
+		audit = strings.Join(c.AuditCommands(), " | ")
+		for i, cmd := range c.Commands {
+			if _, err := exec.LookPath(cmd.Path); err != nil {
+				c.State = ERROR
+				c.TestInfo = append(c.TestInfo, fmt.Sprintf("pipeline stage %d: %s not found", i+1, cmd.Args[0]))
+				opts.log().Warn("pipeline command not found", "check", c.ID, "stage", i+1, "command", cmd.Args[0])
+				return
+			}
+		}
+	} else if _, err := exec.LookPath(shell); err != nil {
+		c.State = ERROR
+		c.TestInfo = append(c.TestInfo, fmt.Sprintf("audit shell %s not found: %v", shell, err))
+		opts.log().Error("audit shell not found", "check", c.ID, "shell", shell, "audit", c.Audit)
+		return
+	} else {
+		// Check if command exists or exit with ERROR.
+		for _, cmd := range c.Commands {
+			if !isShellCommand(shell, cmd.Path) {
+				c.State = ERROR
+				c.TestInfo = append(c.TestInfo, fmt.Sprintf("audit command %s not found", cmd.Path))
+				opts.log().Warn("audit command not found", "check", c.ID, "command", cmd.Path)
+				return
+			}
+		}
+	}
+
+	if isScript(c.Audit) {
+		cmd, cleanup, err := scriptCommand(c.Audit, shell)
+		if err != nil {
+			c.State = ERROR
+			c.TestInfo = append(c.TestInfo, err.Error())
+			opts.log().Warn("audit script could not be prepared", "check", c.ID, "error", err)
 			return
 		}
+		defer cleanup()
+		c.Commands = []*exec.Cmd{cmd}
 	}
 
 	// Run commands.
@@ -118,6 +516,7 @@ func (c *Check) Run() {
 		c.State = WARN
 		return
 	}
+	opts.log().Debug("running audit", "check", c.ID, "commands", strings.Join(c.AuditCommands(), " | "))
 
 	// Each command runs,
 	//   cmd0 out -> cmd1 in, cmd1 out -> cmd2 in ... cmdn out -> os.stdout
@@ -135,8 +534,9 @@ func (c *Check) Run() {
 		cs[i-1].Stdout, err = cs[i].StdinPipe()
 		errmsgs += handleError(
 			err,
-			fmt.Sprintf("failed to run: %s\nfailed command: %s",
-				c.Audit,
+			fmt.Sprintf("failed to run: %s\nfailed command (stage %d of %d): %s",
+				audit,
+				i+1, n,
 				cs[i].Args,
 			),
 		)
@@ -144,27 +544,55 @@ func (c *Check) Run() {
 	}
 
 	// Start command pipeline
+	var startErr error
 	i = 0
 	for i < n {
 		err := cs[i].Start()
 		errmsgs += handleError(
 			err,
-			fmt.Sprintf("failed to run: %s\nfailed command: %s",
-				c.Audit,
+			fmt.Sprintf("failed to run: %s\nfailed command (stage %d of %d): %s",
+				audit,
+				i+1, n,
 				cs[i].Args,
 			),
 		)
+		if err != nil && startErr == nil {
+			startErr = fmt.Errorf("stage %d (%s) failed to start: %v", i+1, cs[i].Args[0], err)
+		}
+		if err != nil && len(c.Pipeline) > 0 {
+			c.TestInfo = append(c.TestInfo, fmt.Sprintf("pipeline stage %d (%s) failed to start: %v", i+1, cs[i].Args[0], err))
+		}
 		i++
 	}
 
+	// Stop the pipeline if it runs for too long or ctx is done
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stopped int32
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			atomic.StoreInt32(&stopped, 1)
+			for _, cmd := range cs {
+				if cmd.Process != nil {
+					cmd.Process.Kill()
+				}
+			}
+		case <-finished:
+		}
+	}()
+
 	// Complete command pipeline
 	i = 0
 	for i < n {
 		err := cs[i].Wait()
 		errmsgs += handleError(
 			err,
-			fmt.Sprintf("failed to run: %s\nfailed command:%s",
-				c.Audit,
+			fmt.Sprintf("failed to run: %s\nfailed command (stage %d of %d): %s",
+				audit,
+				i+1, n,
 				cs[i].Args,
 			),
 		)
@@ -175,17 +603,44 @@ func (c *Check) Run() {
 
 		i++
 	}
+	close(finished)
 
-	finalOutput := c.Tests.execute(out.String())
-	if finalOutput != nil {
-		c.ActualValue = finalOutput.actualResult
-		if finalOutput.testResult {
-			c.State = PASS
-		} else {
-			c.State = FAIL
+	if atomic.LoadInt32(&stopped) == 1 {
+		msg := fmt.Sprintf("audit command timed out after %s", timeout)
+		if ctx.Err() == context.Canceled {
+			msg = "audit command canceled"
 		}
-	} else {
-		errmsgs += handleError(
+		c.State = WARN
+		c.TestInfo = append(c.TestInfo, msg)
+		opts.log().Warn(msg, "check", c.ID, "audit", audit)
+		return
+	}
+
+	if startErr != nil {
+		c.State = ERROR
+		if len(c.Pipeline) == 0 {
+			c.TestInfo = append(c.TestInfo, "audit command "+startErr.Error())
+		}
+		opts.log().Warn("audit command could not be run", "check", c.ID, "error", strings.TrimSpace(errmsgs))
+		return
+	}
+
+	errmsgs += c.evaluate(out.String(), opts)
+
+	if errmsgs != "" {
+		opts.log().Warn("audit command failed", "check", c.ID, "error", strings.TrimSpace(errmsgs))
+	}
+}
+
+// evaluate keeps the output of the audit of the check and sets its state
+// from the result of its tests on the output, inverted if the check is
+// negated. It returns a message for the log if the tests could not be run.
+func (c *Check) evaluate(out string, opts runOptions) string {
+	c.AuditOutput = truncateOutput(out, opts.outputLimit)
+
+	finalOutput := c.Tests.execute(out)
+	if finalOutput == nil {
+		return handleError(
 			fmt.Errorf("final output is nil"),
 			fmt.Sprintf("failed to run: %s\n",
 				c.Audit,
@@ -193,16 +648,126 @@ func (c *Check) Run() {
 		)
 	}
 
-	if errmsgs != "" {
-		glog.V(2).Info(errmsgs)
+	c.ActualValue = finalOutput.actualResult
+	c.ExpectedValue = finalOutput.expectedResult
+	c.PassedTests, c.TotalTests = finalOutput.passed, finalOutput.total
+	result := finalOutput.testResult
+	if c.Negate {
+		// A test passing counts against a negated check.
+		result = !result
+		c.PassedTests = c.TotalTests - c.PassedTests
+		if result {
+			c.TestInfo = append(c.TestInfo, "Negated: passes because its tests failed")
+		} else {
+			c.TestInfo = append(c.TestInfo, "Negated: fails because its tests passed")
+		}
+	}
+	if result {
+		c.State = PASS
+	} else {
+		c.State = FAIL
+		if c.TotalTests > 1 && !c.Negate {
+			c.TestInfo = append(c.TestInfo, fmt.Sprintf("Passed %d/%d tests", c.PassedTests, c.TotalTests))
+		}
+	}
+	return ""
+}
+
+// truncateOutput returns the first limit bytes of s, noting how much was
+// left out, or "" if limit is not positive.
+func truncateOutput(s string, limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	if len(s) <= limit {
+		return s
+	}
+	return fmt.Sprintf("%s... (%d more bytes)", s[:limit], len(s)-limit)
+}
+
+// isScript reports whether the audit of a check is a script of several
+// lines, such as a YAML literal block, rather than a command line.
+func isScript(audit string) bool {
+	return strings.Contains(strings.TrimSpace(audit), "\n")
+}
+
+// scriptCommand writes script to a temporary file that only its owner can
+// read, write or run, and returns the command running it, with the function
+// removing the file once the command has run. The script is run by the
+// interpreter of its shebang line if it has one, and by shell otherwise.
+func scriptCommand(script, shell string) (*exec.Cmd, func(), error) {
+	script = strings.TrimLeft(script, " \t\n")
+
+	args := []string{shell}
+	if strings.HasPrefix(script, "#!") {
+		line := script[2:]
+		if i := strings.Index(line, "\n"); i >= 0 {
+			line = line[:i]
+		}
+		args = strings.Fields(line)
+		if len(args) == 0 {
+			return nil, nil, fmt.Errorf("audit script has an empty shebang line")
+		}
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil, nil, fmt.Errorf("audit script interpreter %s not found: %v", args[0], err)
+	}
+
+	f, err := ioutil.TempFile("", "kube-bench-audit-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create audit script: %v", err)
 	}
+	cleanup := func() { os.Remove(f.Name()) }
+
+	_, err = f.WriteString(script)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0700)
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to write audit script: %v", err)
+	}
+
+	// The interpreter is run on the file, rather than the file itself, so
+	// that scripts also run from temporary directories mounted noexec.
+	return exec.Command(args[0], append(args[1:], f.Name())...), cleanup, nil
+}
+
+// prepareCommands returns the audit commands of the check, from its
+// Pipeline if it has one and from its Audit otherwise.
+func (c *Check) prepareCommands() []*exec.Cmd {
+	if len(c.Pipeline) > 0 {
+		return pipelineToCommands(c.Pipeline)
+	}
+	return textToCommand(c.Audit)
+}
+
+// pipelineToCommands prepares a command for each stage of a pipeline, each
+// stage holding a program followed by its arguments, which are passed to
+// the program as they are rather than being split or unquoted.
+func pipelineToCommands(p [][]string) []*exec.Cmd {
+	cmds := []*exec.Cmd{}
+	for _, stage := range p {
+		if len(stage) == 0 {
+			continue
+		}
+		cmds = append(cmds, exec.Command(stage[0], stage[1:]...))
+	}
+	return cmds
 }
 
 // textToCommand transforms an input text representation of commands to be
-// run into a slice of commands.
+// run into a slice of commands. Scripts, as reported by isScript, have no
+// commands until they are written out to be run.
 // TODO: Make this more robust.
 func textToCommand(s string) []*exec.Cmd {
 	cmds := []*exec.Cmd{}
+	if isScript(s) {
+		return cmds
+	}
 
 	cp := strings.Split(s, "|")
 
@@ -244,13 +809,14 @@ func textToCommand(s string) []*exec.Cmd {
 	return cmds
 }
 
-func isShellCommand(s string) bool {
-	cmd := exec.Command("/bin/sh", "-c", "command -v "+s)
+// isShellCommand reports whether shell finds the command s. It is false if
+// command -v fails, as it does for commands that do not exist.
+func isShellCommand(shell, s string) bool {
+	cmd := exec.Command(shell, "-c", "command -v "+s)
 
 	out, err := cmd.Output()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%s\n", err)
-		os.Exit(1)
+		return false
 	}
 
 	if strings.Contains(string(out), s) {
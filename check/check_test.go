@@ -1,7 +1,14 @@
 package check
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCheck_Run(t *testing.T) {
@@ -28,3 +35,331 @@ func TestCheck_Run(t *testing.T) {
 		}
 	}
 }
+
+func TestParseNodeType(t *testing.T) {
+	for _, nt := range NodeTypes() {
+		parsed, err := ParseNodeType(nt.String())
+		if err != nil {
+			t.Errorf("unexpected error parsing %s: %v", nt, err)
+		}
+		if parsed != nt {
+			t.Errorf("expected %s, got %s", nt, parsed)
+		}
+	}
+
+	for _, s := range []string{"", "Master", "worker"} {
+		if _, err := ParseNodeType(s); err == nil {
+			t.Errorf("expected an error parsing %q", s)
+		}
+	}
+}
+
+func TestCheck_RunTimeout(t *testing.T) {
+	c := &Check{
+		Audit:  "sleep 5",
+		Scored: true,
+		Tests:  &tests{TestItems: []*testItem{{Flag: "done", Set: true}}},
+	}
+	c.Commands = textToCommand(c.Audit)
+
+	start := time.Now()
+	c.run(context.Background(), runOptions{timeout: 100 * time.Millisecond, shell: DefaultShell})
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("check was not stopped after timeout, ran for %s", elapsed)
+	}
+	if c.State != WARN {
+		t.Errorf("expected WARN after timeout, got %s", c.State)
+	}
+	if len(c.TestInfo) != 1 || !strings.Contains(c.TestInfo[0], "timed out") {
+		t.Errorf("expected timeout note in test info, got %q", c.TestInfo)
+	}
+}
+
+func TestCheck_RunMissingShell(t *testing.T) {
+	c := &Check{
+		ID:     "1.1.1",
+		Audit:  "echo hello",
+		Scored: true,
+	}
+	c.Commands = textToCommand(c.Audit)
+	c.run(context.Background(), runOptions{timeout: DefaultTimeout, shell: "/nonexistent/sh"})
+
+	if c.State != ERROR {
+		t.Errorf("expected check to error without a shell, got %s", c.State)
+	}
+	if len(c.TestInfo) != 1 || !strings.Contains(c.TestInfo[0], "/nonexistent/sh not found") {
+		t.Errorf("expected a missing shell message, got %q", c.TestInfo)
+	}
+}
+
+func TestCheck_RunMissingCommand(t *testing.T) {
+	c := &Check{
+		ID:     "1.1.1",
+		Audit:  "no-such-binary --version | grep 1",
+		Scored: true,
+	}
+	c.Commands = textToCommand(c.Audit)
+	c.Run()
+
+	if c.State != ERROR {
+		t.Errorf("expected check to error with a missing command, got %s", c.State)
+	}
+	if len(c.TestInfo) != 1 || c.TestInfo[0] != "audit command no-such-binary not found" {
+		t.Errorf("expected a missing command message, got %q", c.TestInfo)
+	}
+}
+
+func TestCheck_AuditCommands(t *testing.T) {
+	c := &Check{Audit: "ps -ef | grep kube-apiserver | grep -v grep"}
+	c.Commands = textToCommand(c.Audit)
+
+	exp := []string{"ps -ef", "grep kube-apiserver", "grep -v grep"}
+	if cmds := c.AuditCommands(); !reflect.DeepEqual(cmds, exp) {
+		t.Errorf("expected %q, got %q", exp, cmds)
+	}
+}
+
+func TestCheck_RemediationFor(t *testing.T) {
+	c := &Check{
+		Remediation: "Edit the kubelet configuration.",
+		Remediations: map[string]string{
+			"systemd": "Edit /etc/systemd/system/kubelet.service.d/10-kubeadm.conf.",
+			"kubeadm": "Edit /var/lib/kubelet/config.yaml.",
+		},
+	}
+
+	cases := []struct {
+		distro string
+		exp    string
+	}{
+		{distro: "kubeadm", exp: "Edit the kubelet configuration.\nEdit /var/lib/kubelet/config.yaml."},
+		{distro: "", exp: "Edit the kubelet configuration.\nkubeadm: Edit /var/lib/kubelet/config.yaml.\nsystemd: Edit /etc/systemd/system/kubelet.service.d/10-kubeadm.conf."},
+		{distro: "openshift", exp: "Edit the kubelet configuration.\nkubeadm: Edit /var/lib/kubelet/config.yaml.\nsystemd: Edit /etc/systemd/system/kubelet.service.d/10-kubeadm.conf."},
+	}
+
+	for _, tc := range cases {
+		if r := c.RemediationFor(tc.distro); r != tc.exp {
+			t.Errorf("%q: expected %q, got %q", tc.distro, tc.exp, r)
+		}
+	}
+
+	if r := (&Check{Remediation: "fix it"}).RemediationFor("kubeadm"); r != "fix it" {
+		t.Errorf("expected the single remediation, got %q", r)
+	}
+}
+
+func TestCheck_RunAuditOutput(t *testing.T) {
+	newCheck := func() *Check {
+		c := &Check{
+			ID:     "1.1.1",
+			Audit:  "echo --anonymous-auth=true",
+			Scored: true,
+			Tests: &tests{TestItems: []*testItem{{
+				Flag:    "--anonymous-auth",
+				Set:     true,
+				Compare: compare{Op: "eq", Value: "false"},
+			}}},
+		}
+		c.Commands = textToCommand(c.Audit)
+		return c
+	}
+
+	c := newCheck()
+	c.Run()
+	if c.State != FAIL || c.AuditOutput != "--anonymous-auth=true\n" || c.ActualValue != "true" {
+		t.Errorf("expected the audit output to be kept, got %s %q %q", c.State, c.AuditOutput, c.ActualValue)
+	}
+
+	c = newCheck()
+	c.run(context.Background(), runOptions{timeout: DefaultTimeout, shell: DefaultShell, outputLimit: 6})
+	if exp := "--anon... (16 more bytes)"; c.AuditOutput != exp {
+		t.Errorf("expected truncated output %q, got %q", exp, c.AuditOutput)
+	}
+}
+
+func TestCheck_RunRetries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The audit only prints the flag once it has run before.
+	marker := filepath.Join(dir, "ran")
+	newCheck := func() *Check {
+		c := &Check{
+			ID:     "1.1.1",
+			Audit:  "sh -c 'test -f " + marker + " && echo --anonymous-auth=false; touch " + marker + "'",
+			Scored: true,
+			Tests: &tests{TestItems: []*testItem{{
+				Flag: "--anonymous-auth",
+				Set:  true,
+			}}},
+		}
+		c.Commands = textToCommand(c.Audit)
+		return c
+	}
+
+	c := newCheck()
+	c.run(context.Background(), runOptions{timeout: DefaultTimeout, shell: DefaultShell})
+	if c.State != FAIL {
+		t.Fatalf("expected the first run to fail, got %s", c.State)
+	}
+
+	os.Remove(marker)
+	c = newCheck()
+	c.run(context.Background(), runOptions{timeout: DefaultTimeout, shell: DefaultShell, retries: 2, backoff: time.Millisecond})
+	if c.State != PASS {
+		t.Errorf("expected the check to pass when retried, got %s", c.State)
+	}
+	if len(c.TestInfo) != 1 || c.TestInfo[0] != "Succeeded after 1 retries" {
+		t.Errorf("expected the retry count in the test info, got %q", c.TestInfo)
+	}
+}
+
+func TestCheck_RunScript(t *testing.T) {
+	for _, audit := range []string{
+		"flag=--anonymous-auth\necho \"$flag=false $(stat -c %a \"$0\") $0\"\n",
+		"#!/bin/sh -e\nflag=--anonymous-auth\necho \"$flag=false $(stat -c %a \"$0\") $0\"\n",
+	} {
+		c := &Check{
+			ID:     "1.1.1",
+			Audit:  audit,
+			Scored: true,
+			Tests: &tests{TestItems: []*testItem{{
+				Flag:    "--anonymous-auth",
+				Set:     true,
+				Compare: compare{Op: "eq", Value: "false"},
+			}}},
+		}
+		c.Commands = textToCommand(c.Audit)
+		if len(c.Commands) != 0 {
+			t.Errorf("expected scripts not to be split into commands, got %q", c.AuditCommands())
+		}
+
+		c.Run()
+		out := strings.Fields(c.AuditOutput)
+		if c.State != PASS || len(out) != 3 || out[1] != "700" {
+			t.Fatalf("expected the script to pass from a private file, got %s %q %q", c.State, c.AuditOutput, c.TestInfo)
+		}
+		if _, err := os.Stat(out[2]); !os.IsNotExist(err) {
+			t.Errorf("expected the script file to be removed, got %v", err)
+		}
+	}
+
+	c := &Check{ID: "1.1.2", Audit: "#!/no/such/interpreter\necho\n", Scored: true}
+	c.Run()
+	if c.State != ERROR || len(c.TestInfo) != 1 || !strings.Contains(c.TestInfo[0], "/no/such/interpreter not found") {
+		t.Errorf("expected a missing interpreter to error, got %s %q", c.State, c.TestInfo)
+	}
+}
+
+func TestCheck_RunPipeline(t *testing.T) {
+	c := &Check{
+		ID: "1.1.1",
+		Pipeline: [][]string{
+			{"echo", "--anonymous-auth=false --profiling=true"},
+			{"tr", " ", "\n"},
+			{"grep", "anonymous"},
+		},
+		Scored: true,
+		Tests: &tests{TestItems: []*testItem{{
+			Flag:    "--anonymous-auth",
+			Set:     true,
+			Compare: compare{Op: "eq", Value: "false"},
+		}}},
+	}
+	c.Commands = c.prepareCommands()
+
+	exp := []string{"echo --anonymous-auth=false --profiling=true", "tr   \n", "grep anonymous"}
+	if cmds := c.AuditCommands(); !reflect.DeepEqual(cmds, exp) {
+		t.Errorf("expected %q, got %q", exp, cmds)
+	}
+
+	// Pipelines do not need a shell.
+	c.run(context.Background(), runOptions{timeout: DefaultTimeout, shell: "/nonexistent/sh", outputLimit: DefaultAuditOutputLimit})
+	if c.State != PASS || c.AuditOutput != "--anonymous-auth=false\n" {
+		t.Errorf("expected the pipeline to pass, got %s %q %q", c.State, c.AuditOutput, c.TestInfo)
+	}
+
+	c = &Check{ID: "1.1.2", Pipeline: [][]string{{"echo", "x"}, {"no-such-program"}}, Scored: true}
+	c.Commands = c.prepareCommands()
+	c.Run()
+	if c.State != ERROR || len(c.TestInfo) != 1 || c.TestInfo[0] != "pipeline stage 2: no-such-program not found" {
+		t.Errorf("expected a missing program to error, got %s %q", c.State, c.TestInfo)
+	}
+}
+
+func TestCheck_RunPartialTests(t *testing.T) {
+	item := func(flag, value string) *testItem {
+		return &testItem{Flag: flag, Set: true, Compare: compare{Op: "eq", Value: value}}
+	}
+	c := &Check{
+		ID:     "1.1.1",
+		Audit:  "echo --anonymous-auth=false --profiling=false --insecure-port=1 --tls-min-version=VersionTLS12",
+		Scored: true,
+		Tests: &tests{TestItems: []*testItem{
+			item("--anonymous-auth", "false"),
+			item("--profiling", "false"),
+			item("--insecure-port", "0"),
+			item("--tls-min-version", "VersionTLS12"),
+		}},
+	}
+	c.Commands = c.prepareCommands()
+	c.Run()
+
+	if c.State != FAIL || c.PassedTests != 3 || c.TotalTests != 4 {
+		t.Errorf("expected 3/4 tests to pass and the check to fail, got %s %d/%d", c.State, c.PassedTests, c.TotalTests)
+	}
+	if len(c.TestInfo) != 1 || c.TestInfo[0] != "Passed 3/4 tests" {
+		t.Errorf("expected the ratio in TestInfo, got %q", c.TestInfo)
+	}
+
+	c.Tests.TestItems = c.Tests.TestItems[:2]
+	c.reset()
+	c.Run()
+	if c.State != PASS || c.PassedTests != 2 || c.TotalTests != 2 || len(c.TestInfo) != 0 {
+		t.Errorf("expected every test to pass, got %s %d/%d %q", c.State, c.PassedTests, c.TotalTests, c.TestInfo)
+	}
+}
+
+func TestCheck_RunNegate(t *testing.T) {
+	c := &Check{
+		ID:       "1.1.1",
+		Pipeline: [][]string{{"echo", "--insecure-port=8080"}},
+		Scored:   true,
+		Negate:   true,
+		Tests: &tests{TestItems: []*testItem{{
+			Flag:    "--insecure-port",
+			Set:     true,
+			Compare: compare{Op: "noteq", Value: "0"},
+		}}},
+	}
+	c.Commands = c.prepareCommands()
+	c.Run()
+	if c.State != FAIL || c.PassedTests != 0 || len(c.TestInfo) != 1 || c.TestInfo[0] != "Negated: fails because its tests passed" {
+		t.Errorf("expected the negated check to fail, got %s %d/%d %q", c.State, c.PassedTests, c.TotalTests, c.TestInfo)
+	}
+
+	c.Pipeline = [][]string{{"echo", "--insecure-port=0"}}
+	c.Commands = c.prepareCommands()
+	c.reset()
+	c.Run()
+	if c.State != PASS || c.PassedTests != 1 || len(c.TestInfo) != 1 || c.TestInfo[0] != "Negated: passes because its tests failed" {
+		t.Errorf("expected the negated check to pass, got %s %d/%d %q", c.State, c.PassedTests, c.TotalTests, c.TestInfo)
+	}
+
+	// Checks that do not run are not negated.
+	for _, c := range []*Check{
+		{ID: "1.1.2", Type: "manual", Negate: true},
+		{ID: "1.1.3", Type: "skip", Negate: true},
+		{ID: "1.1.4", State: SKIP, Negate: true},
+	} {
+		exp := map[string]State{"manual": WARN, "skip": INFO, "": SKIP}[c.Type]
+		c.Run()
+		if c.State != exp || len(c.TestInfo) != 0 {
+			t.Errorf("%s: expected %s without negation, got %s %q", c.ID, exp, c.State, c.TestInfo)
+		}
+	}
+}
@@ -15,141 +15,916 @@
 package check
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Controls holds all controls to check for master nodes.
+//
+// A Controls can be run any number of times. Each run selects from every
+// group loaded, clears the results of the previous run and leaves in Groups
+// only the groups it selected, so that Groups and the summaries describe the
+// last run. Reset restores all groups without running them.
 type Controls struct {
 	ID      		string   `yaml:"id" json:"id"`
-	Version 		string   `json:"version"`
-	Text    		string   `json:"text"`
-	Type    		NodeType `json:"node_type"`
-	UserCISLevel 	string 	 `json:"cis_level"`
-	Groups  		[]*Group `json:"tests"`
-	Summary
-	// Map level -> Summary
-	SummaryLevelWise map[string]*Summary
+	Version 		string   `yaml:"version" json:"version"`
+	Text    		string   `yaml:"text" json:"text"`
+	Type    		NodeType `yaml:"type" json:"node_type"`
+	UserCISLevel 	string 	 `yaml:"cis_level" json:"cis_level"`
+	Groups  		[]*Group `yaml:"groups" json:"tests"`
+	Summary 		`yaml:",inline"`
+
+	// MinKubeBenchVersion and MaxKubeBenchVersion bound the versions of
+	// kube-bench the controls are meant for, see CheckCompatibility.
+	MinKubeBenchVersion string `yaml:"min_kube_bench_version,omitempty" json:"-"`
+	MaxKubeBenchVersion string `yaml:"max_kube_bench_version,omitempty" json:"-"`
+
+	// Map level -> Summary. LevelSummaries returns the same summaries in
+	// order.
+	SummaryLevelWise map[string]*Summary `yaml:"summary_level_wise"`
+
+	// StartTime and EndTime are when the last run started and finished.
+	StartTime time.Time `yaml:"-" json:"start_time"`
+	EndTime   time.Time `yaml:"-" json:"end_time"`
+
+	// Workers is the number of checks within a group that are run
+	// concurrently. Values below 2 run checks sequentially.
+	Workers int `yaml:"-" json:"-"`
+
+	// Timeout bounds how long the audit commands of each check may run.
+	// DefaultTimeout is used if it is not set.
+	Timeout time.Duration `yaml:"-" json:"-"`
+
+	// Shell is the shell used to look up the audit commands of checks,
+	// such as "/bin/bash" on nodes without "/bin/sh". DefaultShell is used
+	// if it is not set.
+	Shell string `yaml:"-" json:"-"`
+
+	// Retries is how many more times a check that fails is run, in case
+	// the failure was transient. RetryBackoff is how long to wait before
+	// the first retry, and doubles for each further retry.
+	Retries      int           `yaml:"-" json:"-"`
+	RetryBackoff time.Duration `yaml:"-" json:"-"`
+
+	// ResultCache, if set, holds the results of checks to reuse, rather
+	// than running the checks again, while they are fresh. Results are not
+	// cached if it is not set.
+	ResultCache *ResultCache `yaml:"-" json:"-"`
+
+	// AuditOutputLimit is how many bytes of the output of audit commands
+	// are kept in the AuditOutput of checks. DefaultAuditOutputLimit is used
+	// if it is not set, and output is not kept if it is negative.
+	AuditOutputLimit int `yaml:"-" json:"-"`
+
+	// Deterministic makes JSON and WriteJSON order groups and checks by
+	// ID, so that the output of successive runs can be diffed. Maps, such
+	// as SummaryLevelWise, are always encoded with sorted keys.
+	Deterministic bool `yaml:"-" json:"-"`
+
+	// NoColor disables the colors of WriteTable.
+	NoColor bool `yaml:"-" json:"-"`
+
+	// HidePassed and HideSkipped leave the PASS and SKIP checks out of
+	// WriteTable, Markdown and HTML, so that the problems stand out. The
+	// checks are still counted in the summaries.
+	HidePassed  bool `yaml:"-" json:"-"`
+	HideSkipped bool `yaml:"-" json:"-"`
+
+	// DryRun selects checks as usual but, rather than running them, marks
+	// them SKIP with a note so that the plan can be reviewed.
+	DryRun bool `yaml:"-" json:"-"`
+
+	// Distribution selects the entry of the Remediations of checks that is
+	// reported, such as "kubeadm". All entries are reported if it is not
+	// set.
+	Distribution string `yaml:"-" json:"-"`
+
+	// SkipExcluded includes the checks excluded by RunExcept in the run as
+	// SKIP, rather than leaving them out.
+	SkipExcluded bool `yaml:"-" json:"-"`
+
+	// KubeVersion is the version of Kubernetes being checked, such as
+	// "1.24". Checks whose min_version or max_version exclude it are
+	// skipped. Checks are not gated on versions if it is not set.
+	KubeVersion string `yaml:"-" json:"-"`
+
+	// Platform is the provider or distribution of the cluster being checked,
+	// such as "eks" or "openshift". Checks whose platforms do not include it
+	// are skipped. Checks are not gated on platforms if it is not set.
+	Platform string `yaml:"-" json:"-"`
+
+	// ManagedControlPlane marks every check of master controls NA, for
+	// clusters whose master nodes are run by a provider and cannot be
+	// inspected.
+	ManagedControlPlane bool `yaml:"-" json:"-"`
+
+	// StrictSelection makes RunGroup and RunChecks return an error matching
+	// ErrNoChecksMatched, rather than an empty summary, when they are given
+	// IDs that select no checks, such as a mistyped group ID.
+	StrictSelection bool `yaml:"-" json:"-"`
+
+	// UnmatchedGroupIDs holds the IDs given to the last RunGroup that
+	// selected no group, such as mistyped IDs, in the order given.
+	UnmatchedGroupIDs []string `yaml:"-" json:"-"`
+
+	// TreatWarnAsFail turns the checks that would be WARN into FAIL, noting
+	// the original state in their TestInfo.
+	TreatWarnAsFail bool `yaml:"-" json:"-"`
+
+	// Exceptions maps check IDs to approved deviations whose state
+	// replaces the one computed when the check is run.
+	Exceptions map[string]Exception `yaml:"-" json:"-"`
+
+	// CriticalChecks holds the IDs of checks to treat as critical in
+	// addition to those marked critical in the controls file.
+	CriticalChecks map[string]bool `yaml:"-" json:"-"`
+
+	// DefaultSeverity is the severity given to the checks run that have
+	// none in the controls file. DefaultSeverity of the package is used if
+	// it is not set.
+	DefaultSeverity string `yaml:"-" json:"-"`
+
+	// Logger, if set, receives the diagnostics of runs of the controls
+	// rather than the logger set with SetLogger.
+	Logger Logger `yaml:"-" json:"-"`
+
+	// OnCheck, if set, is called with each check once it has run and been
+	// summarized. Calls are made one at a time, in the order of the checks.
+	OnCheck func(check *Check) `yaml:"-" json:"-"`
+
+	// OnProgress, if set, is called after OnCheck with the number of checks
+	// completed so far and the number of checks selected by the run.
+	OnProgress func(p Progress) `yaml:"-" json:"-"`
+
+	// Errors holds the problems found with individual checks during the
+	// last run. Checks with errors are left out of the summaries.
+	Errors []error `yaml:"-" json:"-"`
+
+	// groups holds every group loaded, as Groups only holds the groups
+	// selected by the last run.
+	groups []*Group
+
+	// results holds the state of each check run so far in the current run,
+	// for checks that depend on them.
+	results map[string]State
+
+	// excluded holds the IDs of the checks RunExcept is not running.
+	excluded map[string]bool
+
+	// progress counts the checks of the current run.
+	progress Progress
+}
+
+// Progress reports how far a run has got.
+type Progress struct {
+	Completed int
+	Total     int
 }
 
 // Group is a collection of similar checks.
 type Group struct {
 	ID     string   `yaml:"id" json:"section"`
-	Pass   int      `json:"pass"`
-	Fail   int      `json:"fail"`
-	Warn   int      `json:"warn"`
-	Skip   int      `json:"skip"`
-	Info   int      `json:"info"`
-	Text   string   `json:"desc"`
-	Checks []*Check `json:"results"`
+	Pass   int      `yaml:"pass" json:"pass"`
+	Fail   int      `yaml:"fail" json:"fail"`
+	Warn   int      `yaml:"warn" json:"warn"`
+	Skip   int      `yaml:"skip" json:"skip"`
+	Info   int      `yaml:"info" json:"info"`
+	NA     int      `yaml:"na" json:"na"`
+	Error  int      `yaml:"error" json:"error"`
+	Text   string   `yaml:"text" json:"desc"`
+	Checks []*Check `yaml:"checks" json:"results"`
+
+	// SubGroups holds the groups nested in the group, such as subsection
+	// 1.1 of section 1. Running a group runs its subgroups, and its counts
+	// include theirs.
+	SubGroups []*Group `yaml:"subgroups" json:"subgroups,omitempty"`
+}
+
+// WorstState returns the most severe state among the results of the group
+// in the last run, where FAIL > ERROR > WARN > INFO > PASS > SKIP > NA, or ""
+// if none of its checks ran.
+func (g *Group) WorstState() State {
+	switch {
+	case g.Fail > 0:
+		return FAIL
+	case g.Error > 0:
+		return ERROR
+	case g.Warn > 0:
+		return WARN
+	case g.Info > 0:
+		return INFO
+	case g.Pass > 0:
+		return PASS
+	case g.Skip > 0:
+		return SKIP
+	case g.NA > 0:
+		return NA
+	}
+	return ""
+}
+
+// PassRatio returns the share of the checks of the group that passed in the
+// last run among those that passed or failed, from 0 to 1, ignoring WARN,
+// INFO, SKIP, NA and ERROR. ok is false if no check passed or failed.
+func (g *Group) PassRatio() (ratio float64, ok bool) {
+	if g.Pass+g.Fail == 0 {
+		return 0, false
+	}
+	return float64(g.Pass) / float64(g.Pass+g.Fail), true
+}
+
+// Remediations returns the remediations of the checks of the group and its
+// subgroups that failed in the last run, in the order of the checks and
+// without repeating the remediations that checks share, as a checklist for
+// fixing the group. Every distribution is included, as for RemediationFor
+// with an empty distribution.
+func (g *Group) Remediations() []string {
+	seen := make(map[string]bool)
+	rs := []string{}
+	for _, group := range flattenGroups([]*Group{g}) {
+		for _, check := range group.Checks {
+			if check.State != FAIL {
+				continue
+			}
+			r := check.RemediationFor("")
+			if r != "" && !seen[r] {
+				seen[r] = true
+				rs = append(rs, r)
+			}
+		}
+	}
+	return rs
+}
+
+// GroupSummary holds the results of a group without its checks.
+type GroupSummary struct {
+	ID    string `json:"section"`
+	Text  string `json:"desc"`
+	Pass  int    `json:"pass"`
+	Fail  int    `json:"fail"`
+	Warn  int    `json:"warn"`
+	Info  int    `json:"info"`
+	Skip  int    `json:"skip"`
+	NA    int    `json:"na"`
+	Error int    `json:"error"`
 }
 
 // Summary is a summary of the results of control checks run.
 type Summary struct {
-	Pass int `json:"total_pass"`
-	Fail int `json:"total_fail"`
-	Warn int `json:"total_warn"`
-	Info int `json:"total_info"`
-	Skip int `json:"total_skip"`
+	Pass int `yaml:"total_pass" json:"total_pass"`
+	Fail int `yaml:"total_fail" json:"total_fail"`
+	Warn int `yaml:"total_warn" json:"total_warn"`
+	Info int `yaml:"total_info" json:"total_info"`
+	Skip int `yaml:"total_skip" json:"total_skip"`
+	NA   int `yaml:"total_na" json:"total_na"`
+	// Error counts the checks whose audit could not be run, so that their
+	// result is unknown, as opposed to checks that ran and failed.
+	Error int `yaml:"total_error" json:"total_error"`
+	// CriticalFail counts the failed checks that are critical.
+	CriticalFail int `yaml:"total_critical_fail" json:"total_critical_fail"`
+}
+
+// Exception overrides the result of a check with State, for example to
+// accept a documented deviation from the benchmark.
+type Exception struct {
+	State         State
+	Justification string
+}
+
+// controlsFile is the layout of a controls file. The leading "controls:"
+// key of the files carries no value.
+type controlsFile struct {
+	Header   interface{} `yaml:"controls"`
+	Controls `yaml:",inline"`
 }
 
-// NewControls instantiates a new master Controls object.
+// NewControls instantiates a new master Controls object. Unknown keys and
+// structural problems in the controls file are reported as errors.
+// Checks without a level, as in legacy controls files, are given one from
+// their profile, such as "Level 2 - Master Node", or else from scored:
+// level 1 if they are scored and level 2 otherwise. A level given with the
+// level key always wins.
 func NewControls(t NodeType, level string, in []byte) (*Controls, error) {
-	c := new(Controls)
+	f := &controlsFile{}
+	c := &f.Controls
 
 	c.UserCISLevel = level
-	err := yaml.Unmarshal(in, c)
+	err := yaml.UnmarshalStrict(in, f)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal YAML: %s", err)
+		return nil, &InvalidYAMLError{Err: err}
 	}
 
 	if t != c.Type {
-		return nil, fmt.Errorf("non-%s controls file specified", t)
+		return nil, &NodeTypeMismatchError{Expected: t, Actual: c.Type}
+	}
+
+	for _, group := range flattenGroups(c.Groups) {
+		for _, check := range group.Checks {
+			check.deriveLevel()
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
 	}
 
 	// Prepare audit commands
-	for _, group := range c.Groups {
+	n := 0
+	for _, group := range flattenGroups(c.Groups) {
+		for _, check := range group.Checks {
+			check.Commands = check.prepareCommands()
+		}
+		n += len(group.Checks)
+	}
+
+	currentLogger().Debug("loaded controls", "id", c.ID, "type", c.Type, "version", c.Version, "checks", n)
+	return c, nil
+}
+
+// NewControlsFromReader is like NewControls, but reads the controls file
+// from r, such as an open file, an HTTP response body or a file of an
+// embed.FS.
+func NewControlsFromReader(t NodeType, level string, r io.Reader) (*Controls, error) {
+	in, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read controls file: %v", err)
+	}
+	return NewControls(t, level, in)
+}
+
+// NewControlsWithVars is like NewControls, but expands $VAR and ${VAR} in the
+// audit command and pipeline arguments of each check to vars["VAR"] before
+// preparing the commands.
+// Variables missing from vars are left as they are written, or are an error
+// if strict is set. Shell parameters such as $1 or $$ are always left as they are.
+func NewControlsWithVars(t NodeType, level string, in []byte, vars map[string]string, strict bool) (*Controls, error) {
+	c, err := NewControls(t, level, in)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := []string{}
+	for _, group := range flattenGroups(c.Groups) {
+		for _, check := range group.Checks {
+			expand := func(token string) string {
+				name := strings.Trim(token, "${}")
+				if v, ok := vars[name]; ok {
+					return v
+				}
+				if strict {
+					missing = append(missing, fmt.Sprintf("check %s: $%s", check.ID, name))
+				}
+				return token
+			}
+			check.Audit = varPattern.ReplaceAllStringFunc(check.Audit, expand)
+			for _, stage := range check.Pipeline {
+				for i, arg := range stage {
+					stage[i] = varPattern.ReplaceAllStringFunc(arg, expand)
+				}
+			}
+			check.Commands = check.prepareCommands()
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("undefined variables in audit commands: %s", strings.Join(missing, "; "))
+	}
+	return c, nil
+}
+
+// varPattern matches the $VAR and ${VAR} substitution variables of audit
+// commands, as opposed to shell parameters such as $1 or $$.
+var varPattern = regexp.MustCompile(`\$(\{[A-Za-z_][A-Za-z0-9_]*\}|[A-Za-z_][A-Za-z0-9_]*)`)
+
+// DetectNodeType returns the node type declared by the type field of a
+// controls file, so that the file can be passed to NewControls without
+// knowing its type beforehand.
+func DetectNodeType(in []byte) (NodeType, error) {
+	f := struct {
+		Type NodeType `yaml:"type"`
+	}{}
+
+	if err := yaml.Unmarshal(in, &f); err != nil {
+		return "", &InvalidYAMLError{Err: err}
+	}
+	if f.Type == "" {
+		return "", fmt.Errorf("controls file has no type")
+	}
+	return f.Type, nil
+}
+
+// Validate checks that every group and check loaded has an ID that no
+// other group or check has, that check levels are integers, and that no
+// check depends on a check of a later group, as groups are run in order,
+// reporting all of the problems found. NewControls validates the controls it loads.
+func (controls *Controls) Validate() error {
+	problems := []string{}
+	groupIDs := make(map[string]int)
+	checkIDs := make(map[string]int)
+	duplicates := []string{}
+
+	// controls.groups is left unset, as NewControlsFromFiles adds groups
+	// to Groups after validating each file.
+	groups := controls.groups
+	if groups == nil {
+		groups = controls.Groups
+	}
+
+	// later holds the group of each check not yet reached, so that
+	// dependencies on the checks of later groups can be reported.
+	later := make(map[string]string)
+	for _, group := range flattenGroups(groups) {
+		for _, check := range group.Checks {
+			later[check.ID] = group.ID
+		}
+	}
+
+	for i, group := range flattenGroups(groups) {
 		for _, check := range group.Checks {
-			check.Commands = textToCommand(check.Audit)
+			delete(later, check.ID)
+		}
+		if group.ID == "" {
+			problems = append(problems, fmt.Sprintf("group %d has no id", i+1))
+		} else {
+			groupIDs[group.ID]++
+			if groupIDs[group.ID] == 2 {
+				duplicates = append(duplicates, "group "+group.ID)
+			}
+		}
+
+		for j, check := range group.Checks {
+			if check.ID == "" {
+				problems = append(problems, fmt.Sprintf("check %d in group %q has no id", j+1, group.ID))
+			} else {
+				checkIDs[check.ID]++
+				if checkIDs[check.ID] == 2 {
+					duplicates = append(duplicates, "check "+check.ID)
+				}
+			}
+			if check.CheckCISLevel != "" {
+				if _, err := strconv.ParseUint(check.CheckCISLevel, 10, 64); err != nil {
+					problems = append(problems, fmt.Sprintf("check %q has invalid level %q", check.ID, check.CheckCISLevel))
+				}
+			}
+			if check.Severity != "" && !isSeverity(check.Severity) {
+				problems = append(problems, fmt.Sprintf("check %q has unknown severity %q", check.ID, check.Severity))
+			}
+			for _, id := range check.DependsOn {
+				if gid, ok := later[id]; ok {
+					problems = append(problems, fmt.Sprintf("check %q depends on check %s of later group %s", check.ID, id, gid))
+				}
+			}
+			if check.Audit != "" && len(check.Pipeline) > 0 {
+				problems = append(problems, fmt.Sprintf("check %q has both an audit and a pipeline", check.ID))
+			}
+			for k, stage := range check.Pipeline {
+				if len(stage) == 0 {
+					problems = append(problems, fmt.Sprintf("check %q has an empty pipeline stage %d", check.ID, k+1))
+				}
+			}
+			if check.Tests != nil {
+				for _, item := range check.Tests.TestItems {
+					if item.Compare.Op != "regex" {
+						continue
+					}
+					if _, err := regexp.Compile(item.Compare.Value); err != nil {
+						problems = append(problems, fmt.Sprintf("check %q has invalid regex %q", check.ID, item.Compare.Value))
+					}
+				}
+			}
+		}
+	}
+
+	if len(duplicates) > 0 {
+		problems = append(problems, "duplicate ids: "+strings.Join(duplicates, ", "))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid controls file: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// NewControlsFromFiles instantiates a Controls object from several controls
+// files of the same node type, such as an upstream CIS file and a file of
+// custom checks. Checks are appended to the group with the same ID, or to a
+// new group if none exists yet; the remaining fields are taken from the
+// first file. A check ID defined in more than one file is an error.
+func NewControlsFromFiles(t NodeType, level string, ins ...[]byte) (*Controls, error) {
+	if len(ins) == 0 {
+		return nil, fmt.Errorf("no %s controls files specified", t)
+	}
+
+	var c *Controls
+	groups := make(map[string]*Group)
+	seen := make(map[string]int)
+
+	for i, in := range ins {
+		f, err := NewControls(t, level, in)
+		if err != nil {
+			return nil, fmt.Errorf("controls file %d: %w", i+1, err)
+		}
+
+		checkIDs := make(map[string]bool)
+		for _, group := range flattenGroups(f.Groups) {
+			for _, check := range group.Checks {
+				if n, ok := seen[check.ID]; ok {
+					return nil, fmt.Errorf("controls file %d: check %s is already defined in controls file %d", i+1, check.ID, n+1)
+				}
+				checkIDs[check.ID] = true
+			}
+		}
+		for id := range checkIDs {
+			seen[id] = i
+		}
+
+		if c == nil {
+			c = f
+			for _, group := range c.Groups {
+				groups[group.ID] = group
+			}
+			continue
+		}
+
+		for _, group := range f.Groups {
+			if g, ok := groups[group.ID]; ok {
+				g.Checks = append(g.Checks, group.Checks...)
+				continue
+			}
+			groups[group.ID] = group
+			c.Groups = append(c.Groups, group)
 		}
 	}
 
+	// Merging can move groups after the groups depending on them.
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
 	return c, nil
 }
 
-// RunGroup runs all checks in a group.
+// RunGroup runs all checks in a group. A group ID also selects the groups
+// below it, so that "1" selects groups "1.1" and "1.2" but not "10.1", and
+// the subgroups of the groups it selects. Groups then holds the groups
+// selected, with their subgroups nested in them.
+// Problems with individual checks are collected in controls.Errors and
+// returned together once the remaining checks have run.
 func (controls *Controls) RunGroup(gids ...string) (Summary, error) {
+	return controls.RunGroupContext(context.Background(), gids...)
+}
+
+// RunGroupContext is like RunGroup, but stops running checks once ctx is
+// done, returning the summary of the checks that completed and ctx.Err().
+func (controls *Controls) RunGroupContext(ctx context.Context, gids ...string) (Summary, error) {
+	return controls.runGroup(ctx, controls.UserCISLevel, gids)
+}
+
+// runGroup runs the groups with the supplied IDs as RunGroupContext does,
+// skipping the checks above the CIS level given by level.
+func (controls *Controls) runGroup(ctx context.Context, level string, gids []string) (Summary, error) {
 	g := []*Group{}
+	controls.Errors = nil
 	controls.SummaryLevelWise = map[string]*Summary{}
-	controls.Summary.Pass, controls.Summary.Fail, controls.Summary.Warn, controls.Summary.Skip, controls.Summary.Info = 0, 0, 0, 0, 0
-	controls.SummaryLevelWise["1"] = &Summary{ 0,0,0,0,0}
-	controls.SummaryLevelWise["2"] = &Summary{ 0,0,0,0,0}
+	controls.Summary = Summary{}
+	controls.results = map[string]State{}
+	controls.progress = Progress{}
+	controls.UnmatchedGroupIDs = nil
+	controls.StartTime = time.Now()
+	defer func() { controls.EndTime = time.Now() }()
 
 	// If no groupid is passed run all group checks.
-	if len(gids) == 0 {
+	filtered := len(gids) > 0
+	if !filtered {
 		gids = controls.getAllGroupIDs()
 	}
 
-	userCISLevel, err := strconv.ParseUint(controls.UserCISLevel, 10, 64)
+	userCISLevel, err := strconv.ParseUint(level, 10, 64)
 	if err != nil{
 		return controls.Summary, fmt.Errorf("%s", "error in parsing User CIS level")
 	}
 
-	for _, group := range controls.Groups {
+	if err := controls.checkKubeVersion(); err != nil {
+		return controls.Summary, err
+	}
 
-		for _, gid := range gids {
-			if gid == group.ID {
-				for _, check := range group.Checks {
-					checkCIS, err := strconv.ParseUint(check.CheckCISLevel, 10, 64)
-					if err != nil{
-						return controls.Summary, fmt.Errorf("%s", "error in parsing Check CIS level")
-					}
-					if userCISLevel < checkCIS{
-						check.State = SKIP
-					}
-					check.Run()
-					check.TestInfo = append(check.TestInfo, check.Remediation)
-					summarize(controls, check)
-					summarizeGroup(group, check)
-					summarizeLevel(controls, check)
-				}
+	if err := ctx.Err(); err != nil {
+		return controls.Summary, err
+	}
+
+	selected := []*Group{}
+	runnable := [][]*Check{}
+	parents := make(map[*Group]*Group)
+
+	// selectGroup selects group and its subgroups, in the order of the
+	// controls file.
+	var selectGroup func(group, parent *Group)
+	selectGroup = func(group, parent *Group) {
+		resetGroup(group)
+		checks := []*Check{}
+		for _, check := range group.Checks {
+			check.reset()
+			checkCIS, err := strconv.ParseUint(check.CheckCISLevel, 10, 64)
+			if err != nil{
+				controls.Errors = append(controls.Errors,
+					fmt.Errorf("check %s: error in parsing Check CIS level %q", check.ID, check.CheckCISLevel))
+				controls.logger().Error("invalid check level", "check", check.ID, "level", check.CheckCISLevel)
+				continue
+			}
+			reason, err := controls.versionSkipReason(check)
+			if err != nil {
+				controls.Errors = append(controls.Errors, err)
+				controls.logger().Error("check cannot be gated on version", "check", check.ID, "error", err)
+				continue
+			}
+			if reason == "" {
+				reason = controls.platformSkipReason(check)
+			}
+			if userCISLevel < checkCIS{
+				check.State = SKIP
+				check.SkipReason = fmt.Sprintf("requires CIS level %d", checkCIS)
+			} else if reason != "" {
+				check.skip(reason)
+			}
+			if check.State == SKIP {
+				controls.logger().Debug("skipping check", "check", check.ID, "reason", check.SkipReason)
+			}
+			checks = append(checks, check)
+		}
+
+		selected = append(selected, group)
+		runnable = append(runnable, checks)
+		parents[group] = parent
+		controls.progress.Total += len(checks)
+
+		for _, sub := range group.SubGroups {
+			selectGroup(sub, group)
+		}
+	}
+
+	var walk func(groups []*Group)
+	walk = func(groups []*Group) {
+		for _, group := range groups {
+			if matchAnyGroupID(gids, group.ID) {
+				selectGroup(group, nil)
+				continue
+			}
+			walk(group.SubGroups)
+		}
+	}
+	walk(controls.allGroups())
+
+	if filtered {
+		controls.UnmatchedGroupIDs = controls.unmatchedGroupIDs(gids)
+	}
+
+	if controls.StrictSelection && filtered && len(selected) == 0 {
+		controls.Groups = g
+		return controls.Summary, fmt.Errorf("%w: %s", ErrNoChecksMatched, strings.Join(gids, ", "))
+	}
 
-				g = append(g, group)
+	for i, group := range selected {
+		for _, check := range controls.execute(ctx, runnable[i]) {
+			controls.record(group, check)
+			for p := parents[group]; p != nil; p = parents[p] {
+				summarizeGroup(p, check)
 			}
 		}
+
+		// Subgroups are reached through the group they are nested in.
+		if parents[group] == nil {
+			g = append(g, group)
+		}
+
+		if err := ctx.Err(); err != nil {
+			controls.Groups = g
+			return controls.Summary, err
+		}
 	}
 
 	controls.Groups = g
-	return controls.Summary, nil
+	controls.logger().Info("ran checks", "type", controls.Type, "summary", controls.Summary.String(), "errors", len(controls.Errors))
+	return controls.Summary, controls.runErrors()
+}
+
+// RunGroupAtLevel is like RunGroup, but skips checks based on level rather
+// than on controls.UserCISLevel, which is neither read nor changed. This
+// allows the same controls to be run at several levels.
+func (controls *Controls) RunGroupAtLevel(level string, gids ...string) (Summary, error) {
+	return controls.runGroup(context.Background(), level, gids)
+}
+
+// RunGroupResult is like RunGroup, but runs a copy of the controls and
+// returns it with the results, leaving the receiver untouched. Several
+// goroutines may call it on the same controls provided none of them
+// modifies the controls, in which case OnCheck may be called concurrently.
+func (controls *Controls) RunGroupResult(gids ...string) (*Controls, error) {
+	c := controls.copyForRun()
+	_, err := c.RunGroup(gids...)
+	return c, err
+}
+
+// RunChecksResult is like RunChecks, but runs a copy of the controls as
+// RunGroupResult does.
+func (controls *Controls) RunChecksResult(ids ...string) (*Controls, error) {
+	c := controls.copyForRun()
+	_, err := c.RunChecks(ids...)
+	return c, err
+}
+
+// Clone returns a deep copy of the controls, including every group and
+// check loaded and the results of the last run, so that the copy can be
+// run without affecting the receiver, for example to keep a parsed copy of
+// a controls file to run repeatedly. Callbacks such as OnCheck are shared.
+func (controls *Controls) Clone() *Controls {
+	c := *controls
+	c.Errors = append([]error(nil), controls.Errors...)
+	c.UnmatchedGroupIDs = cloneStrings(controls.UnmatchedGroupIDs)
+	c.Exceptions = nil
+	c.CriticalChecks = cloneBoolMap(controls.CriticalChecks)
+	c.results = nil
+	c.excluded = cloneBoolMap(controls.excluded)
+	c.SummaryLevelWise = nil
+
+	if controls.Exceptions != nil {
+		c.Exceptions = make(map[string]Exception, len(controls.Exceptions))
+		for id, e := range controls.Exceptions {
+			c.Exceptions[id] = e
+		}
+	}
+	if controls.results != nil {
+		c.results = make(map[string]State, len(controls.results))
+		for id, s := range controls.results {
+			c.results[id] = s
+		}
+	}
+	if controls.SummaryLevelWise != nil {
+		c.SummaryLevelWise = make(map[string]*Summary, len(controls.SummaryLevelWise))
+		for level, s := range controls.SummaryLevelWise {
+			ls := *s
+			c.SummaryLevelWise[level] = &ls
+		}
+	}
+
+	// Groups built by RunChecks hold the same checks as the groups loaded,
+	// so each check is copied once and the copies shared the same way.
+	gc := newGroupCopier()
+	c.groups = gc.copyGroups(controls.groups)
+	c.Groups = gc.copyGroups(controls.Groups)
+	return &c
+}
+
+func cloneBoolMap(m map[string]bool) map[string]bool {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]bool, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// copyForRun returns a copy of the controls, with copies of every group and
+// check loaded, that can be run without affecting the receiver. The audit
+// commands are prepared again, as a command can only be started once.
+func (controls *Controls) copyForRun() *Controls {
+	groups := controls.groups
+	if groups == nil {
+		groups = controls.Groups
+	}
+
+	c := *controls
+	c.Errors = nil
+	c.Summary = Summary{}
+	c.SummaryLevelWise = nil
+	c.groups = nil
+	c.Groups = newGroupCopier().copyGroups(groups)
+	for _, group := range flattenGroups(c.Groups) {
+		for _, check := range group.Checks {
+			check.TestInfo = nil
+		}
+	}
+	return &c
+}
+
+// groupCopier copies groups with their subgroups and checks for Clone and
+// copyForRun. Each group and check is copied once, so that groups and
+// checks shared within the controls are shared the same way by the copies.
+type groupCopier struct {
+	groups map[*Group]*Group
+	checks map[*Check]*Check
+}
+
+func newGroupCopier() *groupCopier {
+	return &groupCopier{groups: make(map[*Group]*Group), checks: make(map[*Check]*Check)}
 }
 
-// RunChecks runs the checks with the supplied IDs.
+// copyGroups returns copies of groups, or nil if groups is nil.
+func (gc *groupCopier) copyGroups(groups []*Group) []*Group {
+	if groups == nil {
+		return nil
+	}
+	copies := make([]*Group, len(groups))
+	for i, group := range groups {
+		copies[i] = gc.copyGroup(group)
+	}
+	return copies
+}
+
+func (gc *groupCopier) copyGroup(group *Group) *Group {
+	if g, ok := gc.groups[group]; ok {
+		return g
+	}
+	g := *group
+	gc.groups[group] = &g
+
+	if group.Checks != nil {
+		g.Checks = make([]*Check, len(group.Checks))
+		for i, check := range group.Checks {
+			cc, ok := gc.checks[check]
+			if !ok {
+				cc = check.clone()
+				gc.checks[check] = cc
+			}
+			g.Checks[i] = cc
+		}
+	}
+	g.SubGroups = gc.copyGroups(group.SubGroups)
+	return &g
+}
+
+// RunChecks runs the checks with the supplied IDs. An ID of the form
+// "1.1.1-1.1.20" selects every check from 1.1.1 through 1.1.20 inclusive,
+// and an ID containing wildcards such as "1.2.*" selects every check whose
+// ID matches it. The groups and checks run keep the order of the controls
+// file, whatever the order of ids. Checks are run whatever their CIS level,
+// as they are selected by ID, so there is no RunChecksAtLevel. As for
+// RunGroup, problems with individual checks are collected in controls.Errors.
 func (controls *Controls) RunChecks(ids ...string) (Summary, error) {
+	return controls.RunChecksContext(context.Background(), ids...)
+}
+
+// RunChecksContext is like RunChecks, but stops running checks once ctx is
+// done, returning the summary of the checks that completed and ctx.Err().
+func (controls *Controls) RunChecksContext(ctx context.Context, ids ...string) (Summary, error) {
 	g := []*Group{}
-	m := make(map[string]*Group)
+	m := make(map[*Group]*Group)
+	controls.Errors = nil
 	controls.SummaryLevelWise = map[string]*Summary{}
-	controls.Summary.Pass, controls.Summary.Fail, controls.Summary.Warn, controls.Summary.Skip, controls.Summary.Info = 0, 0, 0, 0, 0
-	controls.SummaryLevelWise["1"] = &Summary{ 0,0,0,0,0}
-	controls.SummaryLevelWise["2"] = &Summary{ 0,0,0,0,0}
+	controls.Summary = Summary{}
+	controls.results = map[string]State{}
+	controls.progress = Progress{}
+	controls.StartTime = time.Now()
+	defer func() { controls.EndTime = time.Now() }()
 
 	// If no groupid is passed run all group checks.
+	requested := ids
 	if len(ids) == 0 {
 		ids = controls.getAllCheckIDs()
 	}
 
-	for _, group := range controls.Groups {
+	ids, err := controls.expandCheckIDs(ids)
+	if err != nil {
+		return controls.Summary, err
+	}
+
+	if err := controls.checkKubeVersion(); err != nil {
+		return controls.Summary, err
+	}
+
+	for _, group := range controls.everyGroup() {
 		for _, check := range group.Checks {
 			for _, id := range ids {
 				if id == check.ID {
-					check.Run()
-					check.TestInfo = append(check.TestInfo, check.Remediation)
-					summarize(controls, check)
-					summarizeLevel(controls, check)
-
+					check.reset()
+					reason, err := controls.versionSkipReason(check)
+					if err != nil {
+						controls.Errors = append(controls.Errors, err)
+						break
+					}
+					if reason == "" {
+						reason = controls.platformSkipReason(check)
+					}
+					if controls.excluded[check.ID] {
+						check.skip("excluded")
+					} else if reason != "" {
+						check.skip(reason)
+					}
+					controls.progress.Total++
 					// Check if we have already added this checks group.
-					if v, ok := m[group.ID]; !ok {
+					if v, ok := m[group]; !ok {
 						// Create a group with same info
 						w := &Group{
 							ID:     group.ID,
@@ -161,90 +936,949 @@ func (controls *Controls) RunChecks(ids ...string) (Summary, error) {
 						w.Checks = append(w.Checks, check)
 
 						// Add to groups we have visited.
-						m[w.ID] = w
+						m[group] = w
 						g = append(g, w)
 					} else {
 						v.Checks = append(v.Checks, check)
 					}
 
+					break
 				}
 			}
 		}
 	}
 
-	controls.Groups = g
-	return controls.Summary, nil
-}
+	if controls.StrictSelection && len(requested) > 0 && len(g) == 0 {
+		controls.Groups = g
+		return controls.Summary, fmt.Errorf("%w: %s", ErrNoChecksMatched, strings.Join(requested, ", "))
+	}
 
-// JSON encodes the results of last run to JSON.
-func (controls *Controls) JSON() ([]byte, error) {
-	return json.Marshal(controls)
-}
+	for i, group := range g {
+		if err := ctx.Err(); err != nil {
+			controls.Groups = g[:i]
+			return controls.Summary, err
+		}
 
-func (controls *Controls) getAllGroupIDs() []string {
-	var ids []string
+		for _, check := range controls.execute(ctx, group.Checks) {
+			controls.record(group, check)
+		}
+	}
 
-	for _, group := range controls.Groups {
-		ids = append(ids, group.ID)
+	if err := ctx.Err(); err != nil {
+		controls.Groups = g
+		return controls.Summary, err
 	}
-	return ids
-}
 
-func (controls *Controls) getAllCheckIDs() []string {
-	var ids []string
+	controls.Groups = g
+	controls.logger().Info("ran checks", "type", controls.Type, "summary", controls.Summary.String(), "errors", len(controls.Errors))
+	return controls.Summary, controls.runErrors()
+}
 
-	for _, group := range controls.Groups {
-		for _, check := range group.Checks {
-			ids = append(ids, check.ID)
+// RunStream runs the checks with the supplied IDs, or every check if none
+// are given, as RunChecksContext does, and sends each check on the returned
+// channel as soon as it has run and been summarized. The channel is closed
+// once the run is over, at which point the summaries describe the run and
+// controls.Errors holds its problems, including an invalid selection. The
+// checks must be received until the channel is closed or ctx is done, and
+// the controls must not be used meanwhile.
+func (controls *Controls) RunStream(ctx context.Context, ids ...string) <-chan *Check {
+	ch := make(chan *Check)
+	onCheck := controls.OnCheck
+	controls.OnCheck = func(check *Check) {
+		if onCheck != nil {
+			onCheck(check)
+		}
+		select {
+		case ch <- check:
+		case <-ctx.Done():
 		}
 	}
-	return ids
 
-}
+	go func() {
+		defer close(ch)
+		defer func() { controls.OnCheck = onCheck }()
 
-func summarize(controls *Controls, check *Check) {
-	switch check.State {
-	case PASS:
-		controls.Summary.Pass++
-	case FAIL:
-		controls.Summary.Fail++
-	case WARN:
-		controls.Summary.Warn++
-	case INFO:
-		controls.Summary.Info++
-	case SKIP:
-		controls.Summary.Skip++
+		_, err := controls.RunChecksContext(ctx, ids...)
+		if err != nil && len(controls.Errors) == 0 && ctx.Err() == nil {
+			controls.Errors = append(controls.Errors, err)
+		}
+	}()
+	return ch
+}
+
+// RunExcept runs every check but those with the supplied IDs, which may be
+// ranges or patterns as for RunChecks. The excluded checks are left out of
+// the run, or are included as SKIP if controls.SkipExcluded is set.
+func (controls *Controls) RunExcept(ids ...string) (Summary, error) {
+	excluded, err := controls.expandCheckIDs(ids)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	controls.excluded = make(map[string]bool)
+	for _, id := range excluded {
+		controls.excluded[id] = true
+	}
+	defer func() { controls.excluded = nil }()
+
+	run := []string{}
+	for _, id := range controls.getAllCheckIDs() {
+		if controls.SkipExcluded || !controls.excluded[id] {
+			run = append(run, id)
+		}
+	}
+
+	if len(run) == 0 {
+		return Summary{}, fmt.Errorf("all checks are excluded")
+	}
+	return controls.RunChecks(run...)
+}
+
+// RunByTag runs the checks that have at least one of tags, like RunChecks.
+func (controls *Controls) RunByTag(tags ...string) (Summary, error) {
+	want := make(map[string]bool)
+	for _, tag := range tags {
+		want[tag] = true
+	}
+
+	ids := []string{}
+	for _, group := range controls.everyGroup() {
+		for _, check := range group.Checks {
+			for _, tag := range check.Tags {
+				if want[tag] {
+					ids = append(ids, check.ID)
+					break
+				}
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		return Summary{}, fmt.Errorf("no checks tagged %s", strings.Join(tags, ", "))
+	}
+	return controls.RunChecks(ids...)
+}
+
+// record finishes a check that has just run, adds it to the summaries of
+// the controls and group, and passes it to controls.OnCheck.
+func (controls *Controls) record(group *Group, check *Check) {
+	if controls.TreatWarnAsFail && check.State == WARN {
+		check.State = FAIL
+		check.TestInfo = append(check.TestInfo, "Originally WARN, treated as FAIL")
+	}
+
+	if e, ok := controls.Exceptions[check.ID]; ok && !controls.DryRun {
+		check.State = e.State
+		if e.State == SKIP {
+			check.SkipReason = "exception: " + e.Justification
+		}
+		check.TestInfo = append(check.TestInfo, "Exception: "+e.Justification)
+	}
+
+	if controls.CriticalChecks[check.ID] && !check.Critical {
+		check.Critical = true
+		check.runCritical = true
+	}
+	if check.Severity == "" {
+		check.Severity = controls.DefaultSeverity
+		if check.Severity == "" {
+			check.Severity = DefaultSeverity
+		}
+		check.defaultSeverity = true
+	}
+
+	check.TestInfo = append(check.TestInfo, check.remediations(controls.Distribution)...)
+	check.nodeType = controls.Type
+	if check.Timestamp.IsZero() {
+		check.Timestamp = time.Now()
+	}
+	summarize(controls, check)
+	summarizeGroup(group, check)
+	summarizeLevel(controls, check)
+
+	if controls.OnCheck != nil {
+		controls.OnCheck(check)
+	}
+
+	controls.progress.Completed++
+	if controls.OnProgress != nil {
+		controls.OnProgress(controls.progress)
+	}
+}
+
+// runErrors combines controls.Errors into a single error, or returns nil if
+// the last run had no errors.
+func (controls *Controls) runErrors() error {
+	if len(controls.Errors) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, len(controls.Errors))
+	for i, err := range controls.Errors {
+		msgs[i] = err.Error()
 	}
+	return fmt.Errorf("%d checks could not be run: %s", len(msgs), strings.Join(msgs, "; "))
+}
+
+// execute runs checks, using up to controls.Workers goroutines, and returns
+// the checks that completed before ctx was done in their original order.
+// Callers summarize the results afterwards so that summaries are built in
+// order.
+func (controls *Controls) execute(ctx context.Context, checks []*Check) []*Check {
+	opts := defaultRunOptions
+	if controls.Timeout > 0 {
+		opts.timeout = controls.Timeout
+	}
+	if controls.Shell != "" {
+		opts.shell = controls.Shell
+	}
+	if controls.AuditOutputLimit != 0 {
+		opts.outputLimit = controls.AuditOutputLimit
+	}
+	opts.retries, opts.backoff = controls.Retries, controls.RetryBackoff
+	opts.cache = controls.ResultCache
+	opts.logger = controls.logger()
+
+	if controls.ManagedControlPlane && controls.Type == MASTER {
+		for _, check := range checks {
+			if check.State != SKIP {
+				check.State = NA
+				check.TestInfo = append(check.TestInfo, "Not applicable: managed control plane")
+			}
+		}
+		return checks
+	}
+
+	if controls.DryRun {
+		for _, check := range checks {
+			if check.State != SKIP {
+				check.State = SKIP
+				check.SkipReason = "dry run"
+				check.TestInfo = append(check.TestInfo, "not executed (dry run)")
+			}
+		}
+		return checks
+	}
+
+	done := make([]bool, len(checks))
+	run := func(i int) {
+		if ctx.Err() != nil {
+			return
+		}
+		checks[i].run(ctx, opts)
+		done[i] = ctx.Err() == nil
+	}
+
+	// Run the checks in waves of checks whose prerequisites have already
+	// run, so that a check can be skipped if a prerequisite did not pass.
+	pending := make(map[string]bool)
+	wait := []int{}
+	for i, check := range checks {
+		pending[check.ID] = true
+		wait = append(wait, i)
+	}
+
+	for len(wait) > 0 && ctx.Err() == nil {
+		wave, rest := []int{}, []int{}
+		for _, i := range wait {
+			ready := true
+			for _, id := range checks[i].DependsOn {
+				if pending[id] {
+					ready = false
+				}
+			}
+			if ready {
+				wave = append(wave, i)
+			} else {
+				rest = append(rest, i)
+			}
+		}
+
+		if len(wave) == 0 {
+			for _, i := range rest {
+				checks[i].skip("prerequisites depend on each other")
+				done[i] = true
+			}
+			break
+		}
+
+		for _, i := range wave {
+			if reason := controls.unmetPrerequisite(checks[i]); reason != "" {
+				checks[i].skip(reason)
+			}
+		}
+
+		controls.runEach(ctx, wave, run)
+
+		for _, i := range wave {
+			if done[i] {
+				controls.results[checks[i].ID] = checks[i].State
+				delete(pending, checks[i].ID)
+			}
+		}
+		wait = rest
+	}
+
+	ran := []*Check{}
+	for i, check := range checks {
+		if done[i] {
+			ran = append(ran, check)
+		}
+	}
+	return ran
+}
+
+// runEach calls run for each of indexes, using up to controls.Workers
+// goroutines, until ctx is done.
+func (controls *Controls) runEach(ctx context.Context, indexes []int, run func(int)) {
+	if controls.Workers < 2 {
+		for _, i := range indexes {
+			run(i)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	ch := make(chan int)
+
+	for w := 0; w < controls.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ch {
+				run(i)
+			}
+		}()
+	}
+
+feed:
+	for _, i := range indexes {
+		select {
+		case ch <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(ch)
+	wg.Wait()
+}
+
+// unmetPrerequisite returns why a check cannot run given the results of the
+// checks it depends on so far in the run, or "" if it can run.
+func (controls *Controls) unmetPrerequisite(check *Check) string {
+	if check.State == SKIP {
+		return ""
+	}
+	for _, id := range check.DependsOn {
+		state, ok := controls.results[id]
+		if !ok {
+			return fmt.Sprintf("prerequisite %s was not run", id)
+		}
+		if state != PASS {
+			return fmt.Sprintf("prerequisite %s is %s", id, state)
+		}
+	}
+	return ""
+}
+
+// JSON encodes the results of last run to JSON.
+func (controls *Controls) JSON() ([]byte, error) {
+	var b bytes.Buffer
+	if err := controls.WriteJSON(&b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// WriteJSON writes the results of last run to w as JSON, encoding one group
+// at a time rather than the whole controls at once.
+func (controls *Controls) WriteJSON(w io.Writer) error {
+	// Encode everything but the groups, and write the groups in place of
+	// the null they are encoded as.
+	c := *controls
+	c.Groups = nil
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if controls.Groups == nil {
+		_, err := w.Write(b)
+		return err
+	}
+
+	groups := controls.Groups
+	if controls.Deterministic {
+		groups = sortedGroups(groups)
+	}
+
+	placeholder := []byte(`"tests":null`)
+	i := bytes.Index(b, placeholder)
+	if _, err := fmt.Fprintf(w, "%s\"tests\":[", b[:i]); err != nil {
+		return err
+	}
+
+	for j, group := range groups {
+		if j > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		g, err := json.Marshal(group)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(g); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "]%s", b[i+len(placeholder):])
+	return err
+}
+
+// sortedGroups returns copies of groups, with their checks, ordered by ID.
+func sortedGroups(groups []*Group) []*Group {
+	sorted := make([]*Group, len(groups))
+	for i, group := range groups {
+		g := *group
+		g.Checks = append([]*Check(nil), group.Checks...)
+		SortChecks(g.Checks)
+		if group.SubGroups != nil {
+			g.SubGroups = sortedGroups(group.SubGroups)
+		}
+		sorted[i] = &g
+	}
+	sort.SliceStable(sorted, func(a, b int) bool {
+		return CompareCheckIDs(sorted[a].ID, sorted[b].ID) < 0
+	})
+	return sorted
+}
+
+// SummaryJSON encodes the summaries of last run to JSON, with the node type
+// and version of the controls but without the groups and checks, using the
+// same keys as JSON.
+func (controls *Controls) SummaryJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Version          string              `json:"version"`
+		Type             NodeType            `json:"node_type"`
+		SummaryLevelWise map[string]*Summary `json:"SummaryLevelWise"`
+		Summary
+	}{
+		Version:          controls.Version,
+		Type:             controls.Type,
+		SummaryLevelWise: controls.SummaryLevelWise,
+		Summary:          controls.Summary,
+	})
+}
+
+// YAML encodes the results of last run to YAML.
+func (controls *Controls) YAML() ([]byte, error) {
+	return yaml.Marshal(controls)
+}
+
+// GroupSummaries returns the results of last run for each group, without
+// the individual check results.
+func (controls *Controls) GroupSummaries() []GroupSummary {
+	gs := []GroupSummary{}
+	for _, group := range controls.Groups {
+		gs = append(gs, GroupSummary{
+			ID:    group.ID,
+			Text:  group.Text,
+			Pass:  group.Pass,
+			Fail:  group.Fail,
+			Warn:  group.Warn,
+			Info:  group.Info,
+			Skip:  group.Skip,
+			NA:    group.NA,
+			Error: group.Error,
+		})
+	}
+	return gs
+}
+
+// EachCheck calls fn for every check of every group in Groups, and of
+// their subgroups, in order.
+func (controls *Controls) EachCheck(fn func(group *Group, check *Check)) {
+	for _, group := range flattenGroups(controls.Groups) {
+		for _, check := range group.Checks {
+			fn(group, check)
+		}
+	}
+}
+
+// SkipInfo describes a check skipped in the last run.
+type SkipInfo struct {
+	ID     string `json:"test_number"`
+	Text   string `json:"test_desc"`
+	Reason string `json:"reason"`
+}
+
+// SkippedChecks returns the checks skipped in the last run, with the reason
+// each was skipped for.
+func (controls *Controls) SkippedChecks() []SkipInfo {
+	skipped := []SkipInfo{}
+	controls.EachCheck(func(group *Group, check *Check) {
+		if check.State == SKIP {
+			skipped = append(skipped, SkipInfo{ID: check.ID, Text: check.Text, Reason: check.SkipReason})
+		}
+	})
+	return skipped
+}
+
+// Remediation is the remediation of a failed check.
+type Remediation struct {
+	CheckID     string `json:"test_number"`
+	Text        string `json:"test_desc"`
+	Remediation string `json:"remediation"`
+}
+
+// FailedRemediations returns the remediations of the checks that failed in
+// the last run, for controls.Distribution.
+func (controls *Controls) FailedRemediations() []Remediation {
+	rs := []Remediation{}
+	controls.EachCheck(func(group *Group, check *Check) {
+		if check.State == FAIL {
+			rs = append(rs, Remediation{
+				CheckID:     check.ID,
+				Text:        check.Text,
+				Remediation: check.RemediationFor(controls.Distribution),
+			})
+		}
+	})
+	return rs
 }
 
+// UniqueRemediations returns rs without the entries whose remediation
+// matches that of an earlier entry, for checks that share a remediation.
+func UniqueRemediations(rs []Remediation) []Remediation {
+	seen := make(map[string]bool)
+	unique := []Remediation{}
+	for _, r := range rs {
+		if !seen[r.Remediation] {
+			seen[r.Remediation] = true
+			unique = append(unique, r)
+		}
+	}
+	return unique
+}
+
+// FindCheck returns the check with ID id, among all the checks loaded, and
+// whether it was found. The check is the one run, so its state is that of
+// the last run that selected it.
+func (controls *Controls) FindCheck(id string) (*Check, bool) {
+	for _, group := range controls.everyGroup() {
+		for _, check := range group.Checks {
+			if check.ID == id {
+				return check, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// FindGroup returns the group with ID id, among all the groups and
+// subgroups loaded, and whether it was found.
+func (controls *Controls) FindGroup(id string) (*Group, bool) {
+	for _, group := range controls.everyGroup() {
+		if group.ID == id {
+			return group, true
+		}
+	}
+	return nil, false
+}
+
+// TotalChecks returns the number of checks loaded, whichever of them the
+// last run selected.
+func (controls *Controls) TotalChecks() int {
+	n := 0
+	for _, group := range controls.everyGroup() {
+		n += len(group.Checks)
+	}
+	return n
+}
+
+// Selected returns the number of checks selected by the last run.
+func (controls *Controls) Selected() int {
+	return controls.progress.Total
+}
+
+// Executed returns the number of checks completed by the last run, which
+// is less than Selected if the run was stopped early.
+func (controls *Controls) Executed() int {
+	return controls.progress.Completed
+}
+
+// CountByLevel returns the number of checks for each CIS level. Unlike
+// SummaryLevelWise it does not depend on a run, so it can be used right
+// after NewControls.
+func (controls *Controls) CountByLevel() map[string]int {
+	counts := make(map[string]int)
+	for _, group := range controls.everyGroup() {
+		for _, check := range group.Checks {
+			counts[check.CheckCISLevel]++
+		}
+	}
+	return counts
+}
+
+// Levels returns the distinct CIS levels of the checks, ordered as "1"
+// before "2" before "10", for example to offer the levels a controls file
+// uses. Like CountByLevel it does not depend on a run. Checks without a
+// level are left out.
+func (controls *Controls) Levels() []string {
+	levels := []string{}
+	for level := range controls.CountByLevel() {
+		if level != "" {
+			levels = append(levels, level)
+		}
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return CompareCheckIDs(levels[i], levels[j]) < 0
+	})
+	return levels
+}
+
+// SummaryBySeverity returns the summary of the checks of the last run for
+// each severity, such as "high". Unlike CIS levels, which scope the checks
+// run, severities rank the findings.
+func (controls *Controls) SummaryBySeverity() map[string]Summary {
+	summaries := make(map[string]Summary)
+	controls.EachCheck(func(group *Group, check *Check) {
+		if check.State == "" {
+			return
+		}
+		s := summaries[check.Severity]
+		s.addCheck(check)
+		summaries[check.Severity] = s
+	})
+	return summaries
+}
+
+// SlowestChecks returns the n checks of last run that took the longest to
+// run, slowest first.
+func (controls *Controls) SlowestChecks(n int) []*Check {
+	checks := []*Check{}
+	controls.EachCheck(func(group *Group, check *Check) {
+		checks = append(checks, check)
+	})
+
+	sort.SliceStable(checks, func(i, j int) bool {
+		return checks[i].Duration > checks[j].Duration
+	})
+
+	if n < len(checks) {
+		checks = checks[:n]
+	}
+	return checks
+}
+
+// Filter returns a copy of the controls holding only the checks whose state
+// is one of states. Groups keep their subgroups, groups left without checks
+// in them or their subgroups are dropped, and summaries are recomputed from
+// the retained checks; the receiver is not modified.
+func (controls *Controls) Filter(states ...State) *Controls {
+	return controls.filter(func(check *Check) bool {
+		return hasState(check.State, states)
+	})
+}
+
+// Compact returns a copy of the controls without the groups that hold no
+// checks, in them or their subgroups, with summaries recomputed from the remaining checks, for output
+// free of empty groups. The receiver is not modified.
+func (controls *Controls) Compact() *Controls {
+	return controls.filter(func(check *Check) bool { return true })
+}
+
+// filter returns a copy of the controls holding only the checks for which
+// keep returns true, as described for Filter. The copy keeps the options of
+// the receiver, such as Distribution and HidePassed, so that it is reported
+// the same way.
+func (controls *Controls) filter(keep func(check *Check) bool) *Controls {
+	c := *controls
+	c.Errors = append([]error(nil), controls.Errors...)
+	c.UnmatchedGroupIDs = cloneStrings(controls.UnmatchedGroupIDs)
+	c.Summary = Summary{}
+	c.SummaryLevelWise = map[string]*Summary{}
+	c.results = nil
+	c.excluded = nil
+	c.groups = nil
+	c.Groups = filterGroups(controls.Groups, keep)
+	if c.Groups == nil {
+		c.Groups = []*Group{}
+	}
+	c.resummarize()
+	return &c
+}
+
+// filterGroups returns copies of groups, and of their subgroups, holding only
+// the checks for which keep returns true. A group is kept if it or any of its
+// subgroups keeps a check. It returns nil if no group is kept.
+func filterGroups(groups []*Group, keep func(check *Check) bool) []*Group {
+	var kept []*Group
+	for _, group := range groups {
+		g := *group
+		g.Checks = []*Check{}
+		for _, check := range group.Checks {
+			if keep(check) {
+				cc := *check
+				g.Checks = append(g.Checks, &cc)
+			}
+		}
+		g.SubGroups = filterGroups(group.SubGroups, keep)
+
+		if len(g.Checks) > 0 || len(g.SubGroups) > 0 {
+			kept = append(kept, &g)
+		}
+	}
+	return kept
+}
+
+func hasState(s State, states []State) bool {
+	for _, v := range states {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset restores every group loaded to Groups and clears the results of
+// the last run.
+func (controls *Controls) Reset() {
+	controls.Groups = controls.allGroups()
+	controls.Errors = nil
+	controls.SummaryLevelWise = map[string]*Summary{}
+	controls.Summary = Summary{}
+	controls.results = map[string]State{}
+	controls.progress = Progress{}
+	controls.UnmatchedGroupIDs = nil
+	controls.StartTime = time.Time{}
+	controls.EndTime = time.Time{}
+
+	for _, group := range flattenGroups(controls.Groups) {
+		resetGroup(group)
+		for _, check := range group.Checks {
+			check.reset()
+		}
+	}
+}
+
+// allGroups returns every group loaded, including those left out of Groups
+// by the last run.
+func (controls *Controls) allGroups() []*Group {
+	if controls.groups == nil {
+		controls.groups = controls.Groups
+	}
+	return controls.groups
+}
+
+// everyGroup returns every group loaded and, following each group, its
+// subgroups.
+func (controls *Controls) everyGroup() []*Group {
+	return flattenGroups(controls.allGroups())
+}
+
+// flattenGroups returns groups with, following each group, its subgroups.
+func flattenGroups(groups []*Group) []*Group {
+	flat := []*Group{}
+	for _, group := range groups {
+		flat = append(flat, group)
+		flat = append(flat, flattenGroups(group.SubGroups)...)
+	}
+	return flat
+}
+
+func resetGroup(group *Group) {
+	group.Pass, group.Fail, group.Warn, group.Info, group.Skip, group.NA, group.Error = 0, 0, 0, 0, 0, 0, 0
+}
+
+// matchGroupID reports whether gid selects the group with ID id, that is
+// whether id is gid or starts with gid followed by a dot.
+func matchGroupID(gid, id string) bool {
+	return id == gid || strings.HasPrefix(id, gid+".")
+}
+
+// matchAnyGroupID reports whether any of gids selects the group with ID id.
+func matchAnyGroupID(gids []string, id string) bool {
+	for _, gid := range gids {
+		if matchGroupID(gid, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// unmatchedGroupIDs returns the gids that match no group loaded.
+func (controls *Controls) unmatchedGroupIDs(gids []string) []string {
+	var unmatched []string
+	for _, gid := range gids {
+		matched := false
+		for _, group := range controls.everyGroup() {
+			if matchGroupID(gid, group.ID) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, gid)
+		}
+	}
+	return unmatched
+}
+
+func (controls *Controls) getAllGroupIDs() []string {
+	var ids []string
+
+	for _, group := range controls.allGroups() {
+		ids = append(ids, group.ID)
+	}
+	return ids
+}
+
+func (controls *Controls) getAllCheckIDs() []string {
+	var ids []string
+
+	for _, group := range controls.everyGroup() {
+		for _, check := range group.Checks {
+			ids = append(ids, check.ID)
+		}
+	}
+	return ids
+
+}
+
+// expandCheckIDs replaces any range expressions and wildcard patterns in
+// ids with the IDs of the checks they cover, in the order the checks appear
+// in the controls.
+func (controls *Controls) expandCheckIDs(ids []string) ([]string, error) {
+	all := controls.getAllCheckIDs()
+	known := make(map[string]bool, len(all))
+	for _, id := range all {
+		known[id] = true
+	}
+
+	expanded := []string{}
+	for _, id := range ids {
+		if !known[id] && strings.ContainsAny(id, "*?[") {
+			matched := false
+			for _, c := range all {
+				ok, err := path.Match(id, c)
+				if err != nil {
+					return nil, fmt.Errorf("invalid check pattern %q: %v", id, err)
+				}
+				if ok {
+					expanded = append(expanded, c)
+					matched = true
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("check pattern %q does not match any checks", id)
+			}
+			continue
+		}
+
+		bounds := strings.SplitN(id, "-", 2)
+		if known[id] || len(bounds) != 2 {
+			expanded = append(expanded, id)
+			continue
+		}
+
+		from, to := strings.TrimSpace(bounds[0]), strings.TrimSpace(bounds[1])
+		for _, b := range []string{from, to} {
+			if !known[b] {
+				return nil, fmt.Errorf("invalid check range %q: no check with ID %q", id, b)
+			}
+		}
+		if CompareCheckIDs(from, to) > 0 {
+			return nil, fmt.Errorf("invalid check range %q: %s comes after %s", id, from, to)
+		}
+
+		for _, c := range all {
+			if CompareCheckIDs(c, from) >= 0 && CompareCheckIDs(c, to) <= 0 {
+				expanded = append(expanded, c)
+			}
+		}
+	}
+
+	return expanded, nil
+}
+
+// CompareCheckIDs compares two dotted check IDs segment by segment, comparing
+// numeric segments by value, so that "1.2" sorts before "1.10", and other
+// segments lexically. An ID sorts before the longer IDs it is a prefix of.
+// It returns -1, 0 or 1 as a sorts before, with or after b.
+func CompareCheckIDs(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, errx := strconv.Atoi(as[i])
+		y, erry := strconv.Atoi(bs[i])
+		if errx == nil && erry == nil {
+			if x != y {
+				if x < y {
+					return -1
+				}
+				return 1
+			}
+		}
+
+		// Numeric segments written differently, such as "01" and "1", are
+		// ordered lexically so that distinct IDs never compare equal.
+		if c := strings.Compare(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// SortChecks sorts checks by ID in the order of CompareCheckIDs, keeping
+// the order of checks with the same ID.
+func SortChecks(checks []*Check) {
+	sort.SliceStable(checks, func(i, j int) bool {
+		return CompareCheckIDs(checks[i].ID, checks[j].ID) < 0
+	})
+}
+
+// summarize adds check to the summary of the controls. The summaries of a
+// run are only built by the goroutine running it, once checks have been
+// collected from the workers, so they are counted without the locking of
+// SummaryAccumulator.
+func summarize(controls *Controls, check *Check) {
+	controls.Summary.addCheck(check)
+}
+
+// summarizeGroup adds check to the counts of group, as summarize does.
 func summarizeGroup(group *Group, check *Check) {
-	switch check.State {
-	case PASS:
-		group.Pass++
-	case FAIL:
-		group.Fail++
-	case WARN:
-		group.Warn++
-	case INFO:
-		group.Info++
-	case SKIP:
-		group.Skip++
+	if n := group.counter(check.State); n != nil {
+		*n++
 	}
 }
 
-func summarizeLevel(control *Controls, check *Check) {
+// counter returns the field of g counting the checks in state, as
+// Summary.counter does.
+func (g *Group) counter(state State) *int {
+	return stateCounter(state, &g.Pass, &g.Fail, &g.Warn, &g.Info, &g.Skip, &g.NA, &g.Error)
+}
 
-	switch check.State{
-	case PASS:
-		control.SummaryLevelWise[check.CheckCISLevel].Pass++
-	case FAIL:
-		control.SummaryLevelWise[check.CheckCISLevel].Fail++
-	case WARN:
-		control.SummaryLevelWise[check.CheckCISLevel].Warn++
-	case INFO:
-		control.SummaryLevelWise[check.CheckCISLevel].Info++
-	case SKIP:
-		control.SummaryLevelWise[check.CheckCISLevel].Skip++
+// summarizeLevel adds check to the summary of its CIS level, as summarize
+// does, creating the summary the first time a level is seen. Checks without
+// a level are counted under the empty level.
+func summarizeLevel(control *Controls, check *Check) {
+	if control.SummaryLevelWise == nil {
+		control.SummaryLevelWise = map[string]*Summary{}
 	}
 
-}
\ No newline at end of file
+	s, ok := control.SummaryLevelWise[check.CheckCISLevel]
+	if !ok {
+		s = &Summary{}
+		control.SummaryLevelWise[check.CheckCISLevel] = s
+	}
+	s.addCheck(check)
+}
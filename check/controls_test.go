@@ -1,8 +1,20 @@
 package check
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 )
@@ -39,3 +51,2316 @@ func TestYamlFiles(t *testing.T) {
 		}
 	}
 }
+
+func TestControls_Filter(t *testing.T) {
+	c := &Controls{
+		ID: "1",
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", State: PASS, CheckCISLevel: "1"},
+					{ID: "1.1.2", State: FAIL, CheckCISLevel: "1"},
+				},
+			},
+			{
+				ID: "1.2",
+				Checks: []*Check{
+					{ID: "1.2.1", State: PASS, CheckCISLevel: "2"},
+				},
+			},
+			{
+				ID: "1.3",
+				Checks: []*Check{
+					{ID: "1.3.1", State: WARN, CheckCISLevel: "2"},
+				},
+			},
+		},
+		Summary: Summary{Pass: 2, Fail: 1, Warn: 1},
+	}
+
+	f := c.Filter(FAIL, WARN)
+
+	if len(f.Groups) != 2 || f.Groups[0].ID != "1.1" || f.Groups[1].ID != "1.3" {
+		t.Fatalf("unexpected filtered groups: %+v", f.Groups)
+	}
+	if len(f.Groups[0].Checks) != 1 || f.Groups[0].Checks[0].ID != "1.1.2" {
+		t.Errorf("unexpected filtered checks: %+v", f.Groups[0].Checks)
+	}
+	if f.Summary != (Summary{Fail: 1, Warn: 1}) {
+		t.Errorf("unexpected filtered summary: %+v", f.Summary)
+	}
+	if f.Groups[0].Fail != 1 || f.SummaryLevelWise["2"].Warn != 1 {
+		t.Errorf("group and level summaries were not recomputed")
+	}
+
+	if len(c.Groups) != 3 || len(c.Groups[0].Checks) != 2 || c.Summary.Pass != 2 {
+		t.Errorf("original controls were modified: %+v", c)
+	}
+}
+
+func TestControls_RunChecksArbitraryLevels(t *testing.T) {
+	c := &Controls{
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", Type: "skip", CheckCISLevel: "1"},
+					{ID: "1.1.2", Type: "skip", CheckCISLevel: "3"},
+					{ID: "1.1.3", Type: "skip"},
+				},
+			},
+		},
+	}
+
+	if _, err := c.RunChecks(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, level := range []string{"1", "3", ""} {
+		s, ok := c.SummaryLevelWise[level]
+		if !ok {
+			t.Errorf("missing summary for level %q", level)
+			continue
+		}
+		if s.Info != 1 {
+			t.Errorf("level %q: expected 1 info check, got %d", level, s.Info)
+		}
+	}
+}
+
+func TestControls_RunGroupWorkers(t *testing.T) {
+	checks := []*Check{}
+	for i := 0; i < 20; i++ {
+		typ := "skip"
+		if i%2 == 0 {
+			typ = "manual"
+		}
+		checks = append(checks, &Check{ID: "1.1." + strconv.Itoa(i), Type: typ, CheckCISLevel: "1"})
+	}
+
+	c := &Controls{
+		UserCISLevel: "2",
+		Workers:      4,
+		Groups:       []*Group{{ID: "1.1", Checks: checks}},
+	}
+
+	summary, err := c.RunGroup()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if summary.Warn != 10 || summary.Info != 10 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if c.Groups[0].Warn != 10 || c.Groups[0].Info != 10 {
+		t.Errorf("unexpected group summary: %+v", c.Groups[0])
+	}
+
+	for i, check := range c.Groups[0].Checks {
+		if check.ID != "1.1."+strconv.Itoa(i) {
+			t.Fatalf("check order not preserved: got %s at position %d", check.ID, i)
+		}
+	}
+}
+
+func TestControls_RunGroupCheckErrors(t *testing.T) {
+	c := &Controls{
+		UserCISLevel: "2",
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", Type: "skip", CheckCISLevel: "1"},
+					{ID: "1.1.2", Type: "skip", CheckCISLevel: "one"},
+					{ID: "1.1.3", Type: "manual", CheckCISLevel: "2"},
+				},
+			},
+		},
+	}
+
+	summary, err := c.RunGroup()
+	if err == nil {
+		t.Fatalf("expected an error for the malformed check")
+	}
+
+	if len(c.Errors) != 1 || !strings.Contains(c.Errors[0].Error(), "1.1.2") {
+		t.Errorf("expected one error for check 1.1.2, got %v", c.Errors)
+	}
+	if summary.Info != 1 || summary.Warn != 1 {
+		t.Errorf("remaining checks were not summarized: %+v", summary)
+	}
+}
+
+func TestControls_RunChecksRange(t *testing.T) {
+	newControls := func() *Controls {
+		checks := []*Check{}
+		for i := 1; i <= 12; i++ {
+			checks = append(checks, &Check{ID: "1.1." + strconv.Itoa(i), Type: "skip"})
+		}
+		return &Controls{
+			Groups: []*Group{
+				{ID: "1.1", Checks: checks},
+				{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "skip"}}},
+			},
+		}
+	}
+
+	cases := []struct {
+		ids    []string
+		exp    int
+		expErr bool
+	}{
+		{ids: []string{"1.1.2-1.1.10"}, exp: 9},
+		{ids: []string{"1.1.12-1.2.1"}, exp: 2},
+		{ids: []string{"1.1.1", "1.1.11-1.1.12"}, exp: 3},
+		{ids: []string{"1.1.10-1.1.2"}, expErr: true},
+		{ids: []string{"1.1.1-1.1.99"}, expErr: true},
+	}
+
+	for _, c := range cases {
+		controls := newControls()
+		summary, err := controls.RunChecks(c.ids...)
+		if c.expErr {
+			if err == nil {
+				t.Errorf("%v: expected an error", c.ids)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", c.ids, err)
+			continue
+		}
+		if summary.Info != c.exp {
+			t.Errorf("%v: expected %d checks to run, got %d", c.ids, c.exp, summary.Info)
+		}
+	}
+}
+
+func TestControls_RunChecksExceptions(t *testing.T) {
+	c := &Controls{
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", Type: "manual", Remediation: "fix it"},
+					{ID: "1.1.2", Type: "manual"},
+				},
+			},
+		},
+		Exceptions: map[string]Exception{
+			"1.1.1": {State: INFO, Justification: "approved deviation"},
+		},
+	}
+
+	summary, err := c.RunChecks()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if summary.Info != 1 || summary.Warn != 1 {
+		t.Errorf("summary does not reflect the exception: %+v", summary)
+	}
+
+	check := c.Groups[0].Checks[0]
+	if check.State != INFO {
+		t.Errorf("expected excepted check to be INFO, got %s", check.State)
+	}
+	if len(check.TestInfo) != 2 || check.TestInfo[0] != "Exception: approved deviation" || check.TestInfo[1] != "fix it" {
+		t.Errorf("unexpected test info: %q", check.TestInfo)
+	}
+}
+
+func TestNewControlsFromFiles(t *testing.T) {
+	upstream := []byte(`---
+id: 1
+text: "Master Checks"
+type: "master"
+groups:
+- id: 1.1
+  text: "API Server"
+  checks:
+  - id: 1.1.1
+    text: "upstream check"
+    type: "skip"
+`)
+	custom := []byte(`---
+type: "master"
+groups:
+- id: 1.1
+  checks:
+  - id: 1.1.100
+    text: "custom check in existing group"
+    type: "skip"
+- id: 9.1
+  text: "Custom"
+  checks:
+  - id: 9.1.1
+    text: "custom check in new group"
+    type: "skip"
+`)
+	duplicate := []byte(`---
+type: "master"
+groups:
+- id: 9.1
+  checks:
+  - id: 1.1.1
+    text: "duplicate check"
+`)
+
+	c, err := NewControlsFromFiles(MASTER, "2", upstream, custom)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if c.Text != "Master Checks" || len(c.Groups) != 2 {
+		t.Fatalf("unexpected merged controls: %+v", c)
+	}
+	if len(c.Groups[0].Checks) != 2 || c.Groups[0].Checks[1].ID != "1.1.100" {
+		t.Errorf("custom check was not appended to existing group: %+v", c.Groups[0].Checks)
+	}
+	if c.Groups[1].ID != "9.1" || len(c.Groups[1].Checks) != 1 {
+		t.Errorf("custom group was not added: %+v", c.Groups[1])
+	}
+
+	summary, err := c.RunChecks()
+	if err != nil {
+		t.Fatalf("unexpected error running merged controls: %v", err)
+	}
+	if summary.Info != 3 {
+		t.Errorf("expected 3 checks to run, got %+v", summary)
+	}
+
+	if _, err := NewControlsFromFiles(MASTER, "2", upstream, duplicate); err == nil {
+		t.Errorf("expected an error for a duplicate check ID")
+	}
+	if _, err := NewControlsFromFiles(NODE, "2", upstream); err == nil {
+		t.Errorf("expected an error for a mismatched node type")
+	}
+}
+
+func TestControls_GroupSummaries(t *testing.T) {
+	c := &Controls{
+		Groups: []*Group{
+			{
+				ID:   "1.1",
+				Text: "API Server",
+				Checks: []*Check{
+					{ID: "1.1.1", Type: "manual"},
+					{ID: "1.1.2", Type: "skip"},
+				},
+			},
+			{
+				ID:   "1.2",
+				Text: "Scheduler",
+				Checks: []*Check{
+					{ID: "1.2.1", Type: "manual"},
+				},
+			},
+		},
+	}
+
+	if _, err := c.RunChecks("1.1.1", "1.1.2", "1.2.1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := []GroupSummary{
+		{ID: "1.1", Text: "API Server", Warn: 1, Info: 1},
+		{ID: "1.2", Text: "Scheduler", Warn: 1},
+	}
+	if gs := c.GroupSummaries(); !reflect.DeepEqual(gs, exp) {
+		t.Errorf("expected %+v, got %+v", exp, gs)
+	}
+}
+
+func TestControls_RunChecksContext(t *testing.T) {
+	c := &Controls{
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{{ID: "1.1.1", Type: "skip"}}},
+			{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "skip"}}},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	summary, err := c.RunChecksContext(ctx)
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if summary.Info != 0 {
+		t.Errorf("expected no checks to run, got %+v", summary)
+	}
+}
+
+func TestControls_RunGroupContextCancelInFlight(t *testing.T) {
+	slow := &Check{
+		ID:            "1.1.1",
+		Audit:         "sleep 5",
+		Scored:        true,
+		CheckCISLevel: "1",
+		Tests:         &tests{TestItems: []*testItem{{Flag: "done", Set: true}}},
+	}
+	slow.Commands = textToCommand(slow.Audit)
+
+	c := &Controls{
+		UserCISLevel: "1",
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{{ID: "1.1.0", Type: "skip", CheckCISLevel: "1"}, slow}},
+			{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "skip", CheckCISLevel: "1"}}},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	summary, err := c.RunGroupContext(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("run was not stopped, took %s", elapsed)
+	}
+	if summary.Info != 1 || summary.Warn != 0 {
+		t.Errorf("expected only the completed check to be summarized, got %+v", summary)
+	}
+	if len(c.Groups) != 1 {
+		t.Errorf("expected only the partially run group, got %d groups", len(c.Groups))
+	}
+}
+
+func TestControls_SlowestChecks(t *testing.T) {
+	sleep := func(id, d string) *Check {
+		c := &Check{
+			ID:     id,
+			Audit:  "sleep " + d,
+			Scored: true,
+			Tests:  &tests{TestItems: []*testItem{{Flag: "done", Set: false}}},
+		}
+		c.Commands = textToCommand(c.Audit)
+		return c
+	}
+
+	c := &Controls{
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{sleep("1.1.1", "0"), sleep("1.1.2", "0.3")}},
+			{ID: "1.2", Checks: []*Check{sleep("1.2.1", "0.1"), {ID: "1.2.2", Type: "skip"}}},
+		},
+	}
+
+	if _, err := c.RunChecks(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	slowest := c.SlowestChecks(2)
+	if len(slowest) != 2 || slowest[0].ID != "1.1.2" || slowest[1].ID != "1.2.1" {
+		t.Fatalf("unexpected slowest checks: %+v", slowest)
+	}
+	if slowest[0].DurationMS < 300 {
+		t.Errorf("expected duration of at least 300ms, got %dms", slowest[0].DurationMS)
+	}
+
+	if n := len(c.SlowestChecks(10)); n != 4 {
+		t.Errorf("expected all 4 checks, got %d", n)
+	}
+}
+
+func TestControls_RunChecksPattern(t *testing.T) {
+	newControls := func() *Controls {
+		return &Controls{
+			Groups: []*Group{
+				{ID: "1.1", Checks: []*Check{{ID: "1.1.1", Type: "skip"}, {ID: "1.1.2", Type: "skip"}}},
+				{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "skip"}, {ID: "1.2.10", Type: "skip"}}},
+				{ID: "2.1", Checks: []*Check{{ID: "2.1.1", Type: "skip"}}},
+			},
+		}
+	}
+
+	cases := []struct {
+		ids    []string
+		exp    int
+		expErr bool
+	}{
+		{ids: []string{"1.2.*"}, exp: 2},
+		{ids: []string{"1.*"}, exp: 4},
+		{ids: []string{"*.1.1"}, exp: 2},
+		{ids: []string{"1.1.*", "2.1.1"}, exp: 3},
+		{ids: []string{"3.*"}, expErr: true},
+		{ids: []string{"1.[2"}, expErr: true},
+	}
+
+	for _, c := range cases {
+		summary, err := newControls().RunChecks(c.ids...)
+		if c.expErr {
+			if err == nil {
+				t.Errorf("%v: expected an error", c.ids)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", c.ids, err)
+			continue
+		}
+		if summary.Info != c.exp {
+			t.Errorf("%v: expected %d checks to run, got %d", c.ids, c.exp, summary.Info)
+		}
+	}
+}
+
+func TestControls_RunGroupDryRun(t *testing.T) {
+	c := &Controls{
+		UserCISLevel: "1",
+		DryRun:       true,
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", Audit: "false", Scored: true, CheckCISLevel: "1"},
+					{ID: "1.1.2", Type: "manual", CheckCISLevel: "2"},
+				},
+			},
+			{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "manual", CheckCISLevel: "1"}}},
+		},
+		Exceptions: map[string]Exception{"1.1.1": {State: INFO}},
+	}
+
+	summary, err := c.RunGroup("1.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if summary.Skip != 2 || summary.Skip != summary.Pass+summary.Fail+summary.Warn+summary.Info+summary.Skip {
+		t.Errorf("expected all selected checks to be skipped, got %+v", summary)
+	}
+	if len(c.Groups) != 1 {
+		t.Fatalf("expected the selected group only, got %d groups", len(c.Groups))
+	}
+
+	planned, skipped := c.Groups[0].Checks[0], c.Groups[0].Checks[1]
+	if len(planned.TestInfo) == 0 || planned.TestInfo[0] != "not executed (dry run)" {
+		t.Errorf("expected dry run note for planned check, got %q", planned.TestInfo)
+	}
+	if len(skipped.TestInfo) > 0 && skipped.TestInfo[0] == "not executed (dry run)" {
+		t.Errorf("level skipped check should not be reported as planned")
+	}
+}
+
+// validate that the files we're shipping load as controls
+func TestNewControlsShippedFiles(t *testing.T) {
+	files, err := filepath.Glob(cfgDir + "*/*.yaml")
+	if err != nil {
+		t.Fatalf("error listing controls files: %v", err)
+	}
+
+	for _, file := range files {
+		if filepath.Base(file) == "config.yaml" {
+			continue
+		}
+
+		in, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("error opening file %s: %v", file, err)
+		}
+
+		var header struct {
+			Type NodeType `yaml:"type"`
+		}
+		if err := yaml.Unmarshal(in, &header); err != nil {
+			t.Fatalf("failed to load YAML from %s: %v", file, err)
+		}
+
+		if _, err := NewControls(header.Type, "2", in); err != nil {
+			t.Errorf("failed to load controls from %s: %v", file, err)
+		}
+	}
+}
+
+func TestNewControlsValidation(t *testing.T) {
+	cases := []struct {
+		in       string
+		problems []string
+	}{
+		{
+			in: `---
+controls:
+type: "master"
+groups:
+- id: 1.1
+  checks:
+  - id: 1.1.1
+    level: 1
+`,
+		},
+		{
+			in: `---
+type: "master"
+groups:
+- text: "no id"
+  checks:
+  - text: "no id either"
+    level: one
+- id: 1.2
+  checks:
+  - id: 1.2.1
+    level: 2a
+`,
+			problems: []string{"group 1 has no id", `check 1 in group "" has no id`, `check "1.2.1" has invalid level "2a"`},
+		},
+		{
+			in: `---
+type: "master"
+groups:
+- id: 1.1
+  checks:
+  - id: 1.1.1
+    score: true
+`,
+			problems: []string{"field score not found"},
+		},
+		{
+			in: `---
+type: "master"
+groups:
+- id: 1.1
+  checks:
+  - id: 1.1.1
+    tests:
+      test_items:
+      - flag: "--tls-min-version"
+        compare:
+          op: regex
+          value: "VersionTLS1[23"
+        set: true
+`,
+			problems: []string{`check "1.1.1" has invalid regex "VersionTLS1[23"`},
+		},
+		{
+			in: `---
+type: "master"
+groups:
+- id: 1.1
+  checks:
+  - id: 1.1.1
+    depends_on: ["1.1.2", "1.2.1"]
+  - id: 1.1.2
+- id: 1.2
+  checks:
+  - id: 1.2.1
+    depends_on: ["1.1.1"]
+`,
+			problems: []string{`check "1.1.1" depends on check 1.2.1 of later group 1.2`},
+		},
+		{
+			in: `---
+type: "master"
+groups:
+- id: 1.1
+  checks:
+  - id: 1.1.1
+    severity: high
+  - id: 1.1.2
+    severity: severe
+`,
+			problems: []string{`check "1.1.2" has unknown severity "severe"`},
+		},
+		{
+			in: `---
+type: "master"
+groups:
+- id: 1.1
+  checks:
+  - id: 1.1.1
+    audit: "ps -ef"
+    pipeline:
+    - [ps, -ef]
+  - id: 1.1.2
+    pipeline:
+    - [ps, -ef]
+    - []
+`,
+			problems: []string{`check "1.1.1" has both an audit and a pipeline`, `check "1.1.2" has an empty pipeline stage 2`},
+		},
+		{
+			in: `---
+type: "master"
+groups:
+- id: 1.1
+  checks:
+  - id: 1.1.1
+  - id: 1.1.2
+- id: 1.2
+  checks:
+  - id: 1.1.1
+  - id: 1.2.3
+  - id: 1.2.3
+  - id: 1.2.3
+- id: 1.2
+`,
+			problems: []string{"duplicate ids: check 1.1.1, check 1.2.3, group 1.2"},
+		},
+	}
+
+	for i, c := range cases {
+		_, err := NewControls(MASTER, "2", []byte(c.in))
+		if len(c.problems) == 0 {
+			if err != nil {
+				t.Errorf("case %d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+
+		if err == nil {
+			t.Errorf("case %d: expected an error", i)
+			continue
+		}
+		for _, p := range c.problems {
+			if !strings.Contains(err.Error(), p) {
+				t.Errorf("case %d: expected error to mention %q, got %v", i, p, err)
+			}
+		}
+	}
+}
+
+func TestControls_OnCheck(t *testing.T) {
+	c := &Controls{
+		UserCISLevel: "2",
+		Workers:      2,
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{{ID: "1.1.1", Type: "skip", CheckCISLevel: "1"}, {ID: "1.1.2", Type: "manual", CheckCISLevel: "1"}}},
+			{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "skip", CheckCISLevel: "1"}}},
+		},
+	}
+
+	seen := []string{}
+	c.OnCheck = func(check *Check) {
+		if check.State == "" {
+			t.Errorf("callback for %s called before the check ran", check.ID)
+		}
+		seen = append(seen, fmt.Sprintf("%s:%d", check.ID, c.Summary.Info+c.Summary.Warn))
+	}
+
+	if _, err := c.RunGroup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := []string{"1.1.1:1", "1.1.2:2", "1.2.1:3"}
+	if !reflect.DeepEqual(seen, exp) {
+		t.Errorf("expected callbacks %v, got %v", exp, seen)
+	}
+}
+
+func TestControls_RunChecksCritical(t *testing.T) {
+	failing := func(id string, critical bool) *Check {
+		c := &Check{
+			ID:            id,
+			Audit:         "echo --anonymous-auth=true",
+			Scored:        true,
+			Critical:      critical,
+			CheckCISLevel: "1",
+			Tests: &tests{TestItems: []*testItem{{
+				Flag:    "--anonymous-auth",
+				Set:     true,
+				Compare: compare{Op: "eq", Value: "false"},
+			}}},
+		}
+		c.Commands = textToCommand(c.Audit)
+		return c
+	}
+
+	c := &Controls{
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{failing("1.1.1", true), failing("1.1.2", false), failing("1.1.3", false)}},
+		},
+		CriticalChecks: map[string]bool{"1.1.2": true},
+	}
+
+	summary, err := c.RunChecks()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if summary.Fail != 3 || summary.CriticalFail != 2 {
+		t.Errorf("expected 3 failures of which 2 critical, got %+v", summary)
+	}
+	if c.SummaryLevelWise["1"].CriticalFail != 2 {
+		t.Errorf("expected 2 critical failures at level 1, got %+v", c.SummaryLevelWise["1"])
+	}
+	if summary.CriticalExitCode() != ExitFail {
+		t.Errorf("expected critical failures to give exit code %d", ExitFail)
+	}
+
+	// Checks made critical by a run are not critical in the next.
+	c.CriticalChecks = nil
+	summary, err = c.RunChecks()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.CriticalFail != 1 || c.Groups[0].Checks[1].Critical || !c.Groups[0].Checks[0].Critical {
+		t.Errorf("expected only the check critical in the controls file to be critical, got %+v", summary)
+	}
+}
+
+func TestControls_YAML(t *testing.T) {
+	c := &Controls{
+		ID:      "1",
+		Version: "1.13",
+		Text:    "Master Node Security Configuration",
+		Type:    MASTER,
+		Groups: []*Group{
+			{
+				ID:   "1.1",
+				Text: "API Server",
+				Checks: []*Check{
+					{ID: "1.1.1", Text: "manual check", Type: "manual", Remediation: "Fix it."},
+				},
+			},
+		},
+	}
+
+	if _, err := c.RunChecks("1.1.1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out, err := c.YAML()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, s := range []string{
+		"version: \"1.13\"",
+		"text: Master Node Security Configuration",
+		"type: master",
+		"total_warn: 1",
+		"status: WARN",
+	} {
+		if !strings.Contains(string(out), s) {
+			t.Errorf("expected output to contain %q, got:\n%s", s, out)
+		}
+	}
+
+	got := new(Controls)
+	if err := yaml.UnmarshalStrict(out, got); err != nil {
+		t.Fatalf("could not parse output: %v", err)
+	}
+	if got.Summary != c.Summary || got.Groups[0].Checks[0].State != WARN {
+		t.Errorf("expected output to round trip, got %+v", got)
+	}
+}
+
+func TestControls_CountByLevel(t *testing.T) {
+	c, err := NewControls(MASTER, "1", []byte(`---
+id: 1
+text: "Master Checks"
+type: "master"
+groups:
+- id: 1.1
+  text: "API Server"
+  checks:
+  - id: 1.1.1
+    text: "level 1 check"
+    level: 1
+  - id: 1.1.2
+    text: "level 2 check"
+    level: 2
+- id: 1.2
+  text: "Scheduler"
+  checks:
+  - id: 1.2.1
+    text: "level 1 check"
+    level: 1
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := map[string]int{"1": 2, "2": 1}
+	if counts := c.CountByLevel(); !reflect.DeepEqual(counts, exp) {
+		t.Errorf("expected %v, got %v", exp, counts)
+	}
+}
+
+func TestControls_Levels(t *testing.T) {
+	c := &Controls{
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{
+				{ID: "1.1.1", CheckCISLevel: "10"},
+				{ID: "1.1.2", CheckCISLevel: "2"},
+				{ID: "1.1.3", CheckCISLevel: "1"},
+			}},
+			{ID: "1.2", Checks: []*Check{
+				{ID: "1.2.1", CheckCISLevel: "2"},
+				{ID: "1.2.2"},
+			}},
+		},
+	}
+
+	if levels, exp := c.Levels(), []string{"1", "2", "10"}; !reflect.DeepEqual(levels, exp) {
+		t.Errorf("expected %v, got %v", exp, levels)
+	}
+	if levels := (&Controls{}).Levels(); len(levels) != 0 {
+		t.Errorf("expected no levels, got %v", levels)
+	}
+}
+
+func TestControls_RunGroupAtLevel(t *testing.T) {
+	c := &Controls{
+		UserCISLevel: "1",
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", Type: "skip", CheckCISLevel: "1"},
+					{ID: "1.1.2", Type: "skip", CheckCISLevel: "2"},
+				},
+			},
+		},
+	}
+
+	c.OnCheck = func(check *Check) {
+		if c.UserCISLevel != "1" {
+			t.Errorf("expected user level to be left unchanged during the run, got %q", c.UserCISLevel)
+		}
+	}
+	summary, err := c.RunGroupAtLevel("2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Info != 2 || summary.Skip != 0 {
+		t.Errorf("expected both checks to run at level 2, got %+v", summary)
+	}
+	if c.UserCISLevel != "1" {
+		t.Errorf("expected user level to be left unchanged, got %q", c.UserCISLevel)
+	}
+	c.OnCheck = nil
+
+	summary, err = c.RunGroup()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Info != 1 || summary.Skip != 1 {
+		t.Errorf("expected the level 2 check to be skipped at level 1, got %+v", summary)
+	}
+
+	summary, err = c.RunGroupAtLevel("2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Info != 2 || summary.Skip != 0 {
+		t.Errorf("expected skipped check to run again at level 2, got %+v", summary)
+	}
+}
+
+func TestControls_RunRepeatedly(t *testing.T) {
+	check := &Check{
+		ID:            "1.1.1",
+		Audit:         "echo --anonymous-auth=false",
+		Scored:        true,
+		CheckCISLevel: "1",
+		Remediation:   "fix it",
+		Tests: &tests{TestItems: []*testItem{{
+			Flag:    "--anonymous-auth",
+			Set:     true,
+			Compare: compare{Op: "eq", Value: "false"},
+		}}},
+	}
+	check.Commands = textToCommand(check.Audit)
+
+	c := &Controls{
+		UserCISLevel: "1",
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{check}},
+			{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "skip", CheckCISLevel: "1"}}},
+		},
+	}
+
+	for _, gid := range []string{"1.1", "1.2", "1.1"} {
+		if _, err := c.RunGroup(gid); err != nil {
+			t.Fatalf("group %s: unexpected error: %v", gid, err)
+		}
+		if len(c.Groups) != 1 || c.Groups[0].ID != gid {
+			t.Fatalf("group %s: unexpected groups after run: %+v", gid, c.Groups)
+		}
+	}
+
+	if check.State != PASS || c.Summary != (Summary{Pass: 1}) || c.Groups[0].Pass != 1 {
+		t.Errorf("expected a clean result when running again, got %s and %+v", check.State, c.Summary)
+	}
+	if len(check.TestInfo) != 1 {
+		t.Errorf("expected test info of the last run only, got %q", check.TestInfo)
+	}
+
+	c.Reset()
+	if len(c.Groups) != 2 || c.Summary != (Summary{}) || check.State != "" || c.Groups[0].Pass != 0 {
+		t.Errorf("expected all groups and no results after reset, got %+v", c)
+	}
+
+	if _, err := c.RunChecks("1.1.1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if check.State != PASS {
+		t.Errorf("expected check to pass after reset, got %s", check.State)
+	}
+}
+
+func TestControls_RunGroupResult(t *testing.T) {
+	c := &Controls{
+		UserCISLevel: "1",
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{{ID: "1.1.1", Type: "skip", CheckCISLevel: "1"}}},
+			{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "manual", CheckCISLevel: "1"}}},
+		},
+	}
+
+	results := make([]*Controls, 2)
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i, gid := range []string{"1.1", "1.2"} {
+		wg.Add(1)
+		go func(i int, gid string) {
+			defer wg.Done()
+			results[i], errs[i] = c.RunGroupResult(gid)
+		}(i, gid)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("run %d: unexpected error: %v", i, err)
+		}
+	}
+	if len(results[0].Groups) != 1 || results[0].Summary != (Summary{Info: 1}) {
+		t.Errorf("unexpected result for group 1.1: %+v", results[0])
+	}
+	if len(results[1].Groups) != 1 || results[1].Summary != (Summary{Warn: 1}) {
+		t.Errorf("unexpected result for group 1.2: %+v", results[1])
+	}
+
+	if len(c.Groups) != 2 || c.Summary != (Summary{}) || c.Groups[0].Checks[0].State != "" || c.Groups[1].Info != 0 {
+		t.Errorf("receiver was modified: %+v", c)
+	}
+}
+
+func TestDetectNodeType(t *testing.T) {
+	cases := []struct {
+		in     string
+		exp    NodeType
+		expErr bool
+	}{
+		{in: "---\ncontrols:\nid: 2\ntype: \"node\"\ngroups: []\n", exp: NODE},
+		{in: "---\nid: 1\ntext: \"no type\"\n", expErr: true},
+		{in: "type: [master\n", expErr: true},
+	}
+
+	for _, c := range cases {
+		typ, err := DetectNodeType([]byte(c.in))
+		if c.expErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.in, err)
+			continue
+		}
+		if typ != c.exp {
+			t.Errorf("%q: expected %s, got %s", c.in, c.exp, typ)
+		}
+	}
+}
+
+func TestControls_WriteJSON(t *testing.T) {
+	for _, c := range []*Controls{
+		{
+			ID:   "1",
+			Text: `"tests":null`,
+			Type: MASTER,
+			Groups: []*Group{
+				{ID: "1.1", Pass: 1, Checks: []*Check{{ID: "1.1.1", State: PASS}}},
+				{ID: "1.2", Fail: 1, Checks: []*Check{{ID: "1.2.1", State: FAIL}}},
+			},
+			Summary:          Summary{Pass: 1, Fail: 1},
+			SummaryLevelWise: map[string]*Summary{"1": {Pass: 1, Fail: 1}},
+		},
+		{ID: "2", Groups: []*Group{}},
+		{ID: "3"},
+	} {
+		var b bytes.Buffer
+		if err := c.WriteJSON(&b); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		exp, err := json.Marshal(c)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if b.String() != string(exp) {
+			t.Errorf("expected:\n%s\ngot:\n%s", exp, b.String())
+		}
+	}
+}
+
+func TestControls_JSONDeterministic(t *testing.T) {
+	c := &Controls{
+		ID:            "1",
+		Deterministic: true,
+		Groups: []*Group{
+			{ID: "1.10", Checks: []*Check{{ID: "1.10.1"}}},
+			{ID: "1.2", Checks: []*Check{{ID: "1.2.10"}, {ID: "1.2.9"}}},
+		},
+		SummaryLevelWise: map[string]*Summary{"2": {}, "1": {}, "10": {}},
+	}
+
+	out, err := c.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sorted := *c
+	sorted.Groups = []*Group{
+		{ID: "1.2", Checks: []*Check{{ID: "1.2.9"}, {ID: "1.2.10"}}},
+		{ID: "1.10", Checks: []*Check{{ID: "1.10.1"}}},
+	}
+	exp, err := json.Marshal(sorted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out) != string(exp) {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, out)
+	}
+
+	if c.Groups[0].ID != "1.10" || c.Groups[1].Checks[0].ID != "1.2.10" {
+		t.Errorf("expected the controls to keep their order")
+	}
+}
+
+func TestControls_SummaryJSON(t *testing.T) {
+	c := &Controls{
+		ID:      "1",
+		Version: "1.13",
+		Type:    MASTER,
+		Groups: []*Group{
+			{ID: "1.1", Pass: 1, Checks: []*Check{{ID: "1.1.1", State: PASS}}},
+		},
+		Summary:          Summary{Pass: 1},
+		SummaryLevelWise: map[string]*Summary{"1": {Pass: 1}},
+	}
+
+	out, err := c.SummaryJSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var full, summary map[string]interface{}
+	b, _ := c.JSON()
+	if err := json.Unmarshal(b, &full); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := json.Unmarshal(out, &summary); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := summary["tests"]; ok || len(summary) != 11 {
+		t.Errorf("expected only the summaries, node type and version, got %s", out)
+	}
+	for k, v := range summary {
+		if !reflect.DeepEqual(full[k], v) {
+			t.Errorf("expected %s to be %v as in JSON, got %v", k, full[k], v)
+		}
+	}
+}
+
+func TestControls_RunChecksTreatWarnAsFail(t *testing.T) {
+	c := &Controls{
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", Type: "manual", Remediation: "review it"},
+					{ID: "1.1.2", Type: "manual"},
+					{ID: "1.1.3", Type: "skip"},
+				},
+			},
+		},
+		TreatWarnAsFail: true,
+		Exceptions: map[string]Exception{
+			"1.1.2": {State: WARN, Justification: "signed off"},
+		},
+	}
+
+	summary, err := c.RunChecks()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if summary != (Summary{Fail: 1, Warn: 1, Info: 1}) {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	check := c.Groups[0].Checks[0]
+	if check.State != FAIL || len(check.TestInfo) != 2 || !strings.Contains(check.TestInfo[0], "WARN") {
+		t.Errorf("expected a FAIL noting the original WARN, got %s %q", check.State, check.TestInfo)
+	}
+}
+
+func TestControls_SkippedChecks(t *testing.T) {
+	c := &Controls{
+		UserCISLevel: "1",
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", Text: "level 1", Type: "skip", CheckCISLevel: "1"},
+					{ID: "1.1.2", Text: "level 2", Type: "skip", CheckCISLevel: "2"},
+					{ID: "1.1.3", Text: "excepted", Type: "manual", CheckCISLevel: "1"},
+				},
+			},
+		},
+		Exceptions: map[string]Exception{
+			"1.1.3": {State: SKIP, Justification: "not applicable"},
+		},
+	}
+
+	if _, err := c.RunGroup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := []SkipInfo{
+		{ID: "1.1.2", Text: "level 2", Reason: "requires CIS level 2"},
+		{ID: "1.1.3", Text: "excepted", Reason: "exception: not applicable"},
+	}
+	if skipped := c.SkippedChecks(); !reflect.DeepEqual(skipped, exp) {
+		t.Errorf("expected %+v, got %+v", exp, skipped)
+	}
+}
+
+func TestControls_RunChecksDistribution(t *testing.T) {
+	c := &Controls{
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{{
+					ID:           "1.1.1",
+					Type:         "manual",
+					Remediations: map[string]string{"kubeadm": "kubeadm steps", "systemd": "systemd steps"},
+				}},
+			},
+		},
+		Distribution: "systemd",
+	}
+
+	if _, err := c.RunChecks(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if info := c.Groups[0].Checks[0].TestInfo; !reflect.DeepEqual(info, []string{"systemd steps"}) {
+		t.Errorf("expected only the systemd remediation, got %q", info)
+	}
+}
+
+func TestControls_RunChecksOrder(t *testing.T) {
+	c := &Controls{
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{{ID: "1.1.1", Type: "skip"}, {ID: "1.1.2", Type: "skip"}}},
+			{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "skip"}}},
+			{ID: "1.1", Checks: []*Check{{ID: "1.1.3", Type: "skip"}}},
+		},
+	}
+
+	if _, err := c.RunChecks("1.1.3", "1.2.1", "1.1.2", "1.1.1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := []string{}
+	for _, group := range c.Groups {
+		for _, check := range group.Checks {
+			got = append(got, group.ID+":"+check.ID)
+		}
+	}
+	exp := []string{"1.1:1.1.1", "1.1:1.1.2", "1.2:1.2.1", "1.1:1.1.3"}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected source order %v, got %v", exp, got)
+	}
+}
+
+func TestControls_RunChecksAnnotations(t *testing.T) {
+	c := &Controls{
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{{ID: "1.1.1", Type: "manual"}}},
+		},
+	}
+	c.Groups[0].Checks[0].Annotations = map[string]string{"waiver": "JIRA-123, expires 2024-12"}
+
+	if _, err := c.RunChecks(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out, err := c.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(out), `"annotations":{"waiver":"JIRA-123, expires 2024-12"}`) {
+		t.Errorf("expected annotations in output, got %s", out)
+	}
+}
+
+func TestControls_FailedRemediations(t *testing.T) {
+	c := &Controls{
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", Text: "first", State: FAIL, Remediation: "restrict permissions"},
+					{ID: "1.1.2", Text: "second", State: PASS, Remediation: "set a flag"},
+				},
+			},
+			{
+				ID: "1.2",
+				Checks: []*Check{
+					{ID: "1.2.1", Text: "third", State: FAIL, Remediation: "restrict permissions"},
+				},
+			},
+		},
+	}
+
+	rs := c.FailedRemediations()
+	exp := []Remediation{
+		{CheckID: "1.1.1", Text: "first", Remediation: "restrict permissions"},
+		{CheckID: "1.2.1", Text: "third", Remediation: "restrict permissions"},
+	}
+	if !reflect.DeepEqual(rs, exp) {
+		t.Errorf("expected %+v, got %+v", exp, rs)
+	}
+
+	if unique := UniqueRemediations(rs); !reflect.DeepEqual(unique, exp[:1]) {
+		t.Errorf("expected %+v, got %+v", exp[:1], unique)
+	}
+}
+
+func TestNewControlsWithVars(t *testing.T) {
+	in := []byte(`---
+id: 2
+text: "Worker Node Security Configuration"
+type: "node"
+groups:
+- id: 2.1
+  text: "Kubelet"
+  checks:
+  - id: 2.1.1
+    text: "kubelet config"
+    audit: "cat ${KUBELET_CONFIG} | awk '{print $1}'"
+    level: 1
+  - id: 2.1.2
+    text: "unknown variable"
+    audit: "stat $KUBELET_SERVICE"
+    level: 1
+  - id: 2.1.3
+    text: "unknown braced variable"
+    audit: "ls ${DIR}_old $1"
+    level: 1
+`)
+	vars := map[string]string{"KUBELET_CONFIG": "/var/lib/kubelet/config.yaml"}
+
+	c, err := NewControlsWithVars(NODE, "1", in, vars, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checks := c.Groups[0].Checks
+	if exp := "cat /var/lib/kubelet/config.yaml | awk '{print $1}'"; checks[0].Audit != exp {
+		t.Errorf("expected audit %q, got %q", exp, checks[0].Audit)
+	}
+	if cmds := checks[0].AuditCommands(); len(cmds) != 2 || cmds[0] != "cat /var/lib/kubelet/config.yaml" {
+		t.Errorf("commands were not prepared from the expanded audit: %q", cmds)
+	}
+	if exp := "stat $KUBELET_SERVICE"; checks[1].Audit != exp {
+		t.Errorf("expected unresolved variable to be kept, got %q", checks[1].Audit)
+	}
+	if exp := "ls ${DIR}_old $1"; checks[2].Audit != exp {
+		t.Errorf("expected unresolved braced variable to be kept, got %q", checks[2].Audit)
+	}
+
+	_, err = NewControlsWithVars(NODE, "1", in, vars, true)
+	if err == nil || !strings.Contains(err.Error(), "check 2.1.2: $KUBELET_SERVICE") {
+		t.Errorf("expected an error for the undefined variable, got %v", err)
+	}
+}
+
+func TestControls_RunGroupDependsOn(t *testing.T) {
+	flagCheck := func(id, flag string, deps ...string) *Check {
+		c := &Check{
+			ID:            id,
+			Audit:         "echo --anonymous-auth=false",
+			Scored:        true,
+			CheckCISLevel: "1",
+			DependsOn:     deps,
+			Tests: &tests{TestItems: []*testItem{{
+				Flag: flag,
+				Set:  true,
+			}}},
+		}
+		c.Commands = textToCommand(c.Audit)
+		return c
+	}
+
+	c := &Controls{
+		UserCISLevel: "1",
+		Workers:      2,
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					// Dependents come first to check that prerequisites run first.
+					flagCheck("1.1.3", "--anonymous-auth", "1.1.1"),
+					flagCheck("1.1.4", "--anonymous-auth", "1.1.2"),
+					flagCheck("1.1.1", "--anonymous-auth"),
+					flagCheck("1.1.2", "--missing-flag"),
+					flagCheck("1.1.5", "--anonymous-auth", "1.1.6"),
+					flagCheck("1.1.6", "--anonymous-auth", "1.1.5"),
+				},
+			},
+			{
+				ID:     "1.2",
+				Checks: []*Check{flagCheck("1.2.1", "--anonymous-auth", "1.1.3", "9.9.9")},
+			},
+		},
+	}
+
+	if _, err := c.RunGroup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := map[string]struct {
+		state  State
+		reason string
+	}{
+		"1.1.1": {PASS, ""},
+		"1.1.2": {FAIL, ""},
+		"1.1.3": {PASS, ""},
+		"1.1.4": {SKIP, "prerequisite 1.1.2 is FAIL"},
+		"1.1.5": {SKIP, "prerequisites depend on each other"},
+		"1.1.6": {SKIP, "prerequisites depend on each other"},
+		"1.2.1": {SKIP, "prerequisite 9.9.9 was not run"},
+	}
+	for _, group := range c.Groups {
+		for _, check := range group.Checks {
+			e := exp[check.ID]
+			if check.State != e.state || check.SkipReason != e.reason {
+				t.Errorf("%s: expected %s %q, got %s %q", check.ID, e.state, e.reason, check.State, check.SkipReason)
+			}
+		}
+	}
+
+	if ids := []string{c.Groups[0].Checks[0].ID, c.Groups[0].Checks[2].ID}; ids[0] != "1.1.3" || ids[1] != "1.1.1" {
+		t.Errorf("check order not preserved: %v", ids)
+	}
+}
+
+func TestControls_OnProgress(t *testing.T) {
+	newControls := func(progress *[]Progress) *Controls {
+		return &Controls{
+			UserCISLevel: "1",
+			Groups: []*Group{
+				{ID: "1.1", Checks: []*Check{{ID: "1.1.1", Type: "skip", CheckCISLevel: "1"}, {ID: "1.1.2", Type: "skip", CheckCISLevel: "2"}}},
+				{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "skip", CheckCISLevel: "1"}}},
+			},
+			OnProgress: func(p Progress) {
+				*progress = append(*progress, p)
+			},
+		}
+	}
+
+	var progress []Progress
+	if _, err := newControls(&progress).RunGroup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp := []Progress{{1, 3}, {2, 3}, {3, 3}}
+	if !reflect.DeepEqual(progress, exp) {
+		t.Errorf("RunGroup: expected %v, got %v", exp, progress)
+	}
+
+	progress = nil
+	if _, err := newControls(&progress).RunChecks("1.1.2", "1.2.1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp = []Progress{{1, 2}, {2, 2}}
+	if !reflect.DeepEqual(progress, exp) {
+		t.Errorf("RunChecks: expected %v, got %v", exp, progress)
+	}
+}
+
+func TestControls_EachCheck(t *testing.T) {
+	c := &Controls{
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{{ID: "1.1.1"}, {ID: "1.1.2"}}},
+			{ID: "1.2"},
+			{ID: "1.3", Checks: []*Check{{ID: "1.3.1"}}},
+		},
+	}
+
+	got := []string{}
+	c.EachCheck(func(group *Group, check *Check) {
+		got = append(got, group.ID+":"+check.ID)
+	})
+
+	exp := []string{"1.1:1.1.1", "1.1:1.1.2", "1.3:1.3.1"}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+}
+
+func TestControls_RunByTag(t *testing.T) {
+	c := &Controls{
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", Type: "skip", Tags: []string{"network"}},
+					{ID: "1.1.2", Type: "skip", Tags: []string{"authentication", "encryption"}},
+					{ID: "1.1.3", Type: "skip"},
+				},
+			},
+			{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "manual", Tags: []string{"encryption"}}}},
+		},
+	}
+
+	summary, err := c.RunByTag("network", "encryption")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary != (Summary{Info: 2, Warn: 1}) {
+		t.Errorf("expected only tagged checks to run, got %+v", summary)
+	}
+
+	if _, err := c.RunByTag("storage"); err == nil {
+		t.Errorf("expected an error when no checks have the tag")
+	}
+}
+
+func TestGroup_WorstState(t *testing.T) {
+	cases := []struct {
+		g   Group
+		exp State
+	}{
+		{Group{Pass: 2, Fail: 1, Warn: 3}, FAIL},
+		{Group{Pass: 2, Warn: 3, Error: 1}, ERROR},
+		{Group{Pass: 2, Warn: 1, Info: 4}, WARN},
+		{Group{Pass: 2, Info: 1, Skip: 5}, INFO},
+		{Group{Pass: 2, Skip: 5}, PASS},
+		{Group{Skip: 5}, SKIP},
+		{Group{}, ""},
+	}
+
+	for _, c := range cases {
+		if s := c.g.WorstState(); s != c.exp {
+			t.Errorf("%+v: expected %q, got %q", c.g, c.exp, s)
+		}
+	}
+}
+
+func TestGroup_PassRatio(t *testing.T) {
+	cases := []struct {
+		g     Group
+		ratio float64
+		ok    bool
+	}{
+		{Group{Pass: 3, Fail: 1, Warn: 4, Skip: 2}, 0.75, true},
+		{Group{Fail: 2}, 0, true},
+		{Group{Pass: 1}, 1, true},
+		{Group{Warn: 1, Info: 1, Skip: 1}, 0, false},
+	}
+
+	for _, c := range cases {
+		if ratio, ok := c.g.PassRatio(); ratio != c.ratio || ok != c.ok {
+			t.Errorf("%+v: expected %v, %v, got %v, %v", c.g, c.ratio, c.ok, ratio, ok)
+		}
+	}
+}
+
+func TestGroup_Remediations(t *testing.T) {
+	g := &Group{
+		ID: "1.1",
+		Checks: []*Check{
+			{ID: "1.1.1", State: FAIL, Remediation: "Set --anonymous-auth=false."},
+			{ID: "1.1.2", State: PASS, Remediation: "Set --profiling=false."},
+			{ID: "1.1.3", State: FAIL, Remediation: "Restart the API server."},
+			{ID: "1.1.4", State: FAIL, Remediation: "Set --anonymous-auth=false."},
+			{ID: "1.1.5", State: WARN, Remediation: "Review the audit policy."},
+			{ID: "1.1.6", State: FAIL},
+		},
+		SubGroups: []*Group{
+			{ID: "1.1.7", Checks: []*Check{
+				{ID: "1.1.7.1", State: FAIL, Remediation: "Restart the API server."},
+				{ID: "1.1.7.2", State: FAIL, Remediation: "Rotate the certificates."},
+			}},
+		},
+	}
+
+	exp := []string{"Set --anonymous-auth=false.", "Restart the API server.", "Rotate the certificates."}
+	if rs := g.Remediations(); !reflect.DeepEqual(rs, exp) {
+		t.Errorf("expected %q, got %q", exp, rs)
+	}
+	if rs := (&Group{}).Remediations(); len(rs) != 0 {
+		t.Errorf("expected no remediations for an empty group, got %q", rs)
+	}
+}
+
+func TestControls_RunExcept(t *testing.T) {
+	newControls := func() *Controls {
+		return &Controls{
+			Groups: []*Group{
+				{ID: "1.1", Checks: []*Check{{ID: "1.1.1", Type: "skip"}, {ID: "1.1.2", Type: "skip"}, {ID: "1.1.3", Type: "skip"}}},
+				{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "skip"}}},
+			},
+		}
+	}
+
+	c := newControls()
+	summary, err := c.RunExcept("1.1.2-1.1.3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary != (Summary{Info: 2}) || len(c.Groups[0].Checks) != 1 {
+		t.Errorf("expected excluded checks to be left out, got %+v", summary)
+	}
+
+	c = newControls()
+	c.SkipExcluded = true
+	summary, err = c.RunExcept("1.2.*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary != (Summary{Info: 3, Skip: 1}) {
+		t.Errorf("expected excluded checks to be skipped, got %+v", summary)
+	}
+	if skipped := c.SkippedChecks(); len(skipped) != 1 || skipped[0].Reason != "excluded" {
+		t.Errorf("unexpected skipped checks: %+v", skipped)
+	}
+
+	if _, err := newControls().RunExcept("1.*"); err == nil {
+		t.Errorf("expected an error when all checks are excluded")
+	}
+}
+
+func TestControls_RunGroupPrefix(t *testing.T) {
+	c := &Controls{
+		UserCISLevel: "1",
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{{ID: "1.1.1", Type: "skip", CheckCISLevel: "1"}}},
+			{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "skip", CheckCISLevel: "1"}}},
+			{ID: "10.1", Checks: []*Check{{ID: "10.1.1", Type: "skip", CheckCISLevel: "1"}}},
+		},
+	}
+
+	summary, err := c.RunGroup("1", "1.2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if summary.Info != 2 || len(c.Groups) != 2 || c.Groups[0].ID != "1.1" || c.Groups[1].ID != "1.2" {
+		t.Errorf("expected groups 1.1 and 1.2 to run once, got %+v", c.Groups)
+	}
+}
+
+func TestControls_TotalChecks(t *testing.T) {
+	c := &Controls{
+		UserCISLevel: "1",
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{{ID: "1.1.1", Type: "skip", CheckCISLevel: "1"}, {ID: "1.1.2", Type: "skip", CheckCISLevel: "1"}}},
+			{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "skip", CheckCISLevel: "1"}}},
+		},
+	}
+
+	if _, err := c.RunGroup("1.2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.TotalChecks() != 3 || c.Selected() != 1 || c.Executed() != 1 {
+		t.Errorf("expected 1 of 3 checks to run, got %d selected and %d executed of %d", c.Selected(), c.Executed(), c.TotalChecks())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.OnCheck = func(*Check) { cancel() }
+	if _, err := c.RunGroupContext(ctx); err == nil {
+		t.Fatalf("expected the run to be canceled")
+	}
+	if c.Selected() != 3 || c.Executed() != 2 {
+		t.Errorf("expected 2 of 3 selected checks to complete, got %d of %d", c.Executed(), c.Selected())
+	}
+}
+
+func TestControls_RunGroupManagedControlPlane(t *testing.T) {
+	newControls := func(typ NodeType) *Controls {
+		return &Controls{
+			Type:                typ,
+			UserCISLevel:        "1",
+			ManagedControlPlane: true,
+			Groups: []*Group{
+				{
+					ID: "1.1",
+					Checks: []*Check{
+						{ID: "1.1.1", Audit: "true", Scored: true, CheckCISLevel: "1"},
+						{ID: "1.1.2", Type: "manual", CheckCISLevel: "2"},
+					},
+				},
+			},
+		}
+	}
+
+	c := newControls(MASTER)
+	summary, err := c.RunGroup()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.NA != 1 || summary.Skip != 1 || summary.Pass != 0 || summary.Warn != 0 {
+		t.Errorf("expected 1 NA and 1 SKIP, got %+v", summary)
+	}
+	if c.Groups[0].NA != 1 || c.Groups[0].Checks[0].State != NA {
+		t.Errorf("expected the group to count the NA check, got %+v", c.Groups[0])
+	}
+	if s := c.SummaryLevelWise["1"]; s == nil || s.NA != 1 {
+		t.Errorf("expected the level summary to count the NA check, got %+v", s)
+	}
+
+	c = newControls(NODE)
+	if summary, _ = c.RunGroup(); summary.NA != 0 {
+		t.Errorf("expected node checks to run, got %+v", summary)
+	}
+}
+
+func TestControls_FindCheck(t *testing.T) {
+	c := &Controls{
+		UserCISLevel: "1",
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{{ID: "1.1.1", Audit: "true", Scored: true, CheckCISLevel: "1"}}},
+			{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "manual", CheckCISLevel: "1"}}},
+		},
+	}
+	if _, err := c.RunGroup("1.2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	check, ok := c.FindCheck("1.2.1")
+	if !ok || check != c.Groups[0].Checks[0] || check.State != WARN {
+		t.Errorf("expected the check run, got %+v, %v", check, ok)
+	}
+	if check, ok := c.FindCheck("1.1.1"); !ok || check.ID != "1.1.1" {
+		t.Errorf("expected checks of groups not run to be found, got %+v, %v", check, ok)
+	}
+	if _, ok := c.FindCheck("1.3.1"); ok {
+		t.Errorf("expected unknown check not to be found")
+	}
+
+	if group, ok := c.FindGroup("1.1"); !ok || group.ID != "1.1" {
+		t.Errorf("expected group 1.1, got %+v, %v", group, ok)
+	}
+	if _, ok := c.FindGroup("1"); ok {
+		t.Errorf("expected group IDs to match exactly")
+	}
+}
+
+func TestControls_Compact(t *testing.T) {
+	c := &Controls{
+		ID: "1",
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{}},
+			{
+				ID:   "1.2",
+				Pass: 5,
+				Checks: []*Check{
+					{ID: "1.2.1", State: PASS, CheckCISLevel: "1"},
+					{ID: "1.2.2", State: FAIL, CheckCISLevel: "1"},
+				},
+			},
+		},
+		Summary: Summary{Pass: 5},
+	}
+
+	compact := c.Compact()
+	if len(compact.Groups) != 1 || compact.Groups[0].ID != "1.2" || len(compact.Groups[0].Checks) != 2 {
+		t.Fatalf("expected only the group with checks, got %+v", compact.Groups)
+	}
+	if compact.Summary != (Summary{Pass: 1, Fail: 1}) || compact.Groups[0].Pass != 1 {
+		t.Errorf("expected summaries recomputed from the checks, got %+v", compact.Summary)
+	}
+	if len(c.Groups) != 2 || c.Groups[1].Pass != 5 || c.Summary.Pass != 5 {
+		t.Errorf("expected the original controls to be unchanged, got %+v", c)
+	}
+}
+
+func TestControls_FilterSubGroups(t *testing.T) {
+	c := &Controls{
+		ID: "1",
+		Groups: []*Group{
+			{ID: "1", SubGroups: []*Group{
+				{ID: "1.1", Checks: []*Check{
+					{ID: "1.1.1", State: PASS, CheckCISLevel: "1"},
+					{ID: "1.1.2", State: FAIL, CheckCISLevel: "1"},
+				}},
+				{ID: "1.2", Checks: []*Check{
+					{ID: "1.2.1", State: PASS, CheckCISLevel: "1"},
+				}},
+			}},
+		},
+	}
+
+	filtered := c.Filter(FAIL)
+	if len(filtered.Groups) != 1 || filtered.Groups[0].ID != "1" {
+		t.Fatalf("expected the parent group to be kept, got %+v", filtered.Groups)
+	}
+	parent := filtered.Groups[0]
+	if len(parent.SubGroups) != 1 || parent.SubGroups[0].ID != "1.1" || len(parent.SubGroups[0].Checks) != 1 {
+		t.Fatalf("expected only subgroup 1.1 with its failing check, got %+v", parent.SubGroups)
+	}
+	if parent.Fail != 1 || parent.Pass != 0 || parent.SubGroups[0].Fail != 1 {
+		t.Errorf("expected the parent counts to include its subgroups, got %+v", parent)
+	}
+	if filtered.Summary != (Summary{Fail: 1}) {
+		t.Errorf("expected summary %+v, got %+v", Summary{Fail: 1}, filtered.Summary)
+	}
+
+	compact := c.Compact()
+	if len(compact.Groups) != 1 || len(compact.Groups[0].SubGroups) != 2 || compact.Groups[0].Pass != 2 {
+		t.Errorf("expected the tree to be kept, got %+v", compact.Groups)
+	}
+}
+
+func TestControls_FilterKeepsOptions(t *testing.T) {
+	c := &Controls{
+		ID:           "1",
+		Distribution: "kubeadm",
+		HidePassed:   true,
+		Groups: []*Group{
+			{ID: "1.1", Text: "API Server", Checks: []*Check{
+				{ID: "1.1.1", Text: "passing check", State: PASS},
+				{
+					ID:           "1.1.2",
+					Text:         "failing check",
+					State:        FAIL,
+					Remediations: map[string]string{"kubeadm": "kubeadm steps", "systemd": "systemd steps"},
+				},
+			}},
+		},
+	}
+
+	for name, copy := range map[string]*Controls{
+		"Filter":  c.Filter(PASS, FAIL),
+		"Compact": c.Compact(),
+	} {
+		for report, hides := range map[string]bool{"Markdown": true, "HTML": true, "CSV": false} {
+			var out []byte
+			var err error
+			switch report {
+			case "Markdown":
+				out, err = copy.Markdown()
+			case "HTML":
+				out, err = copy.HTML()
+			case "CSV":
+				out, err = copy.CSV()
+			}
+			if err != nil {
+				t.Fatalf("%s %s: unexpected error: %v", name, report, err)
+			}
+			if bytes.Contains(out, []byte("passing check")) == hides {
+				t.Errorf("%s %s: expected HidePassed to be kept, got %s", name, report, out)
+			}
+			if !bytes.Contains(out, []byte("kubeadm steps")) || bytes.Contains(out, []byte("systemd steps")) {
+				t.Errorf("%s %s: expected Distribution to be kept, got %s", name, report, out)
+			}
+		}
+	}
+}
+
+func TestControls_Clone(t *testing.T) {
+	in := []byte(`---
+controls:
+id: 1
+text: "Master Node Security Configuration"
+type: "master"
+groups:
+- id: 1.1
+  text: "API Server"
+  checks:
+  - id: 1.1.1
+    text: "passing check"
+    audit: "echo --anonymous-auth=false"
+    tests:
+      test_items:
+      - flag: "--anonymous-auth"
+        compare:
+          op: eq
+          value: false
+        set: true
+    tags: ["api"]
+    scored: true
+    level: "1"
+- id: 1.2
+  text: "Scheduler"
+  checks:
+  - id: 1.2.1
+    text: "manual check"
+    type: "manual"
+    level: "1"
+`)
+	original, err := NewControls(MASTER, "1", in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		clone := original.Clone()
+		summary, err := clone.RunChecks("1.1.1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if summary.Pass != 1 || len(clone.Groups) != 1 {
+			t.Errorf("run %d: expected the clone to pass, got %+v", i, summary)
+		}
+		clone.Groups[0].Checks[0].Tags[0] = "changed"
+		clone.Groups[0].Checks[0].Tests.TestItems[0].Flag = "changed"
+	}
+
+	if original.Summary != (Summary{}) || len(original.Groups) != 2 {
+		t.Errorf("expected the original to be unchanged, got %+v", original)
+	}
+	check := original.Groups[0].Checks[0]
+	if check.State != "" || check.TestInfo != nil || check.Tags[0] != "api" || check.Tests.TestItems[0].Flag != "--anonymous-auth" {
+		t.Errorf("expected the original check to be unchanged, got %+v", check)
+	}
+	for _, cmd := range check.Commands {
+		if cmd.Process != nil {
+			t.Errorf("expected the original commands not to be started")
+		}
+	}
+
+	original.RunChecks("1.2.1")
+	clone := original.Clone()
+	if len(clone.Groups) != 1 || clone.Groups[0] == original.Groups[0] || clone.Warn != 1 {
+		t.Fatalf("expected a copy of the results, got %+v", clone)
+	}
+	if clone.Groups[0].Checks[0] != clone.groups[1].Checks[0] {
+		t.Errorf("expected the groups run and loaded to share their copied checks")
+	}
+	if clone.Reset(); len(clone.Groups) != 2 {
+		t.Errorf("expected the clone to keep every group loaded")
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, fmt.Errorf("connection reset")
+}
+
+func TestNewControlsFromReader(t *testing.T) {
+	f, err := os.Open("data")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	c, err := NewControlsFromReader(MASTER, "2", f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(c.Groups) != len(controls.Groups) || len(c.Groups[0].Checks) != len(controls.Groups[0].Checks) {
+		t.Errorf("expected the same controls as NewControls, got %+v", c)
+	}
+
+	if _, err := NewControlsFromReader(MASTER, "2", errReader{}); err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("expected the read error, got %v", err)
+	}
+	if _, err := NewControlsFromReader(MASTER, "2", strings.NewReader("id: [1\n")); !errors.Is(err, ErrInvalidYAML) {
+		t.Errorf("expected an invalid YAML error, got %v", err)
+	}
+}
+
+func TestControls_StrictSelection(t *testing.T) {
+	c := &Controls{
+		UserCISLevel: "1",
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{{ID: "1.1.1", Type: "manual", CheckCISLevel: "1"}}},
+		},
+	}
+
+	if _, err := c.RunGroup("1.9"); err != nil {
+		t.Errorf("expected no error without StrictSelection, got %v", err)
+	}
+
+	c.StrictSelection = true
+	if _, err := c.RunGroup("1.9", "2"); !errors.Is(err, ErrNoChecksMatched) || err.Error() != "no checks matched the selection: 1.9, 2" {
+		t.Errorf("expected no checks to match, got %v", err)
+	}
+	if len(c.Groups) != 0 {
+		t.Errorf("expected no groups to be run, got %d", len(c.Groups))
+	}
+	if _, err := c.RunChecks("1.1.9", "1.3.1"); !errors.Is(err, ErrNoChecksMatched) || err.Error() != "no checks matched the selection: 1.1.9, 1.3.1" {
+		t.Errorf("expected no checks to match, got %v", err)
+	}
+
+	if summary, err := c.RunChecks("1.1.*"); err != nil || summary.Warn != 1 {
+		t.Errorf("expected the matched check to run, got %+v, %v", summary, err)
+	}
+	if summary, err := c.RunGroup(); err != nil || summary.Warn != 1 {
+		t.Errorf("expected every group to run without IDs, got %+v, %v", summary, err)
+	}
+}
+
+func TestControls_RunGroupSubGroups(t *testing.T) {
+	in := []byte(`---
+controls:
+id: 1
+type: "master"
+groups:
+- id: 1
+  text: "Master Node Configuration"
+  checks:
+  - id: 1.0.1
+    type: "manual"
+    level: 1
+  subgroups:
+  - id: 1.1
+    text: "API Server"
+    checks:
+    - id: 1.1.1
+      type: "manual"
+      level: 1
+    - id: 1.1.2
+      type: "skip"
+      level: 1
+  - id: 1.2
+    text: "Scheduler"
+    checks:
+    - id: 1.2.1
+      type: "skip"
+      level: 1
+- id: 2
+  text: "Etcd"
+  checks:
+  - id: 2.1
+    type: "manual"
+    level: 1
+`)
+	c, err := NewControls(MASTER, "1", in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.TotalChecks() != 5 {
+		t.Errorf("expected the checks of subgroups to be counted, got %d", c.TotalChecks())
+	}
+
+	summary, err := c.RunGroup("1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Warn != 2 || summary.Info != 2 {
+		t.Errorf("expected the checks of the group and its subgroups to run once, got %+v", summary)
+	}
+	if len(c.Groups) != 1 || len(c.Groups[0].SubGroups) != 2 {
+		t.Fatalf("expected the group with its subgroups nested, got %+v", c.Groups)
+	}
+	root, sub := c.Groups[0], c.Groups[0].SubGroups[0]
+	if root.Warn != 2 || root.Info != 2 || sub.Warn != 1 || sub.Info != 1 {
+		t.Errorf("expected the group counts to include its subgroups, got %+v and %+v", root, sub)
+	}
+
+	if summary, _ = c.RunGroup("1.2"); summary.Info != 1 || len(c.Groups) != 1 || c.Groups[0].ID != "1.2" {
+		t.Errorf("expected a subgroup to be run on its own, got %+v, %+v", summary, c.Groups)
+	}
+	if summary, _ = c.RunChecks("1.1.1", "2.1"); summary.Warn != 2 {
+		t.Errorf("expected checks of subgroups to be run by ID, got %+v", summary)
+	}
+
+	if _, ok := c.FindGroup("1.1"); !ok {
+		t.Errorf("expected subgroups to be found")
+	}
+	if _, err := NewControlsFromFiles(MASTER, "1", in, []byte("---\ntype: master\ngroups:\n- id: 3\n  checks:\n  - id: 1.1.2\n")); err == nil {
+		t.Errorf("expected checks of subgroups to be compared across files")
+	}
+}
+
+func TestControls_RunChecksResult(t *testing.T) {
+	c := &Controls{
+		UserCISLevel: "1",
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{{ID: "1.1.1", Type: "skip", CheckCISLevel: "1"}}},
+			{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "manual", CheckCISLevel: "1"}}},
+		},
+	}
+
+	result, err := c.RunChecksResult("1.2.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Groups) != 1 || result.Summary != (Summary{Warn: 1}) {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(c.Groups) != 2 || c.Summary != (Summary{}) || c.Groups[1].Checks[0].State != "" {
+		t.Errorf("receiver was modified: %+v", c)
+	}
+}
+
+func TestControls_SummaryBySeverity(t *testing.T) {
+	c := &Controls{
+		UserCISLevel: "1",
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{
+				{ID: "1.1.1", Type: "manual", CheckCISLevel: "1", Severity: SeverityHigh},
+				{ID: "1.1.2", Type: "manual", CheckCISLevel: "1", Severity: SeverityHigh},
+				{ID: "1.1.3", Type: "skip", CheckCISLevel: "1"},
+			}},
+		},
+	}
+
+	if _, err := c.RunGroup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]Summary{
+		SeverityHigh:    {Warn: 2},
+		DefaultSeverity: {Info: 1},
+	}
+	if !reflect.DeepEqual(c.SummaryBySeverity(), expected) {
+		t.Errorf("expected %v, got %v", expected, c.SummaryBySeverity())
+	}
+
+	out, err := c.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(out), `"severity":"high"`) || !strings.Contains(string(out), `"severity":"medium"`) {
+		t.Errorf("expected severities in JSON output, got %s", out)
+	}
+
+	c.DefaultSeverity = SeverityLow
+	if _, err := c.RunGroup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s := c.SummaryBySeverity()[SeverityLow]; s != (Summary{Info: 1}) {
+		t.Errorf("expected the configured default severity, got %v", c.SummaryBySeverity())
+	}
+}
+
+func TestCompareCheckIDs(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.2", "1.10", -1},
+		{"1.10", "1.2", 1},
+		{"1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.1", -1},
+		{"1.10.1", "1.9.20", 1},
+		{"2", "10", -1},
+		{"1.01", "1.1", -1},
+		{"1.a", "1.b", -1},
+		{"1.2a", "1.10", 1},
+	}
+	for _, c := range cases {
+		if got := CompareCheckIDs(c.a, c.b); got != c.expected {
+			t.Errorf("CompareCheckIDs(%q, %q): expected %d, got %d", c.a, c.b, c.expected, got)
+		}
+	}
+}
+
+func TestSortChecks(t *testing.T) {
+	ids := []string{"1.10.1", "1.2.10", "2.1", "1.2.2", "1.2", "10.1", "1.2.1"}
+	checks := make([]*Check, len(ids))
+	for i, id := range ids {
+		checks[i] = &Check{ID: id}
+	}
+
+	SortChecks(checks)
+
+	expected := []string{"1.2", "1.2.1", "1.2.2", "1.2.10", "1.10.1", "2.1", "10.1"}
+	for i, check := range checks {
+		if check.ID != expected[i] {
+			t.Fatalf("expected order %v, got %s at %d", expected, check.ID, i)
+		}
+	}
+}
+
+func TestControls_RunTimestamps(t *testing.T) {
+	c := &Controls{
+		UserCISLevel: "1",
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{
+				{ID: "1.1.1", Type: "manual", CheckCISLevel: "1"},
+				{ID: "1.1.2", Audit: "sleep 0.01", CheckCISLevel: "1"},
+			}},
+		},
+	}
+
+	before := time.Now()
+	if _, err := c.RunGroup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	after := time.Now()
+
+	if c.StartTime.Before(before) || c.EndTime.After(after) || c.EndTime.Before(c.StartTime) {
+		t.Errorf("expected the run to span %v to %v, got %v to %v", before, after, c.StartTime, c.EndTime)
+	}
+	for _, check := range c.Groups[0].Checks {
+		if check.Timestamp.Before(c.StartTime) || check.Timestamp.After(c.EndTime) {
+			t.Errorf("check %s: expected a timestamp within the run, got %v", check.ID, check.Timestamp)
+		}
+	}
+
+	out, err := c.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded struct {
+		StartTime time.Time `json:"start_time"`
+		Tests     []struct {
+			Results []struct {
+				Timestamp time.Time `json:"timestamp"`
+			} `json:"results"`
+		} `json:"tests"`
+	}
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !decoded.StartTime.Equal(c.StartTime) || !decoded.Tests[0].Results[1].Timestamp.Equal(c.Groups[0].Checks[1].Timestamp) {
+		t.Errorf("expected the timestamps in JSON output, got %s", out)
+	}
+
+	c.Reset()
+	if !c.StartTime.IsZero() || !c.EndTime.IsZero() || !c.Groups[0].Checks[0].Timestamp.IsZero() {
+		t.Errorf("expected Reset to clear the timestamps")
+	}
+}
+
+func TestControls_UnmatchedGroupIDs(t *testing.T) {
+	c := &Controls{
+		UserCISLevel: "1",
+		Groups: []*Group{
+			{ID: "1.1", Checks: []*Check{{ID: "1.1.1", Type: "manual", CheckCISLevel: "1"}}, SubGroups: []*Group{
+				{ID: "1.1.2", Checks: []*Check{{ID: "1.1.2.1", Type: "manual", CheckCISLevel: "1"}}},
+			}},
+			{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "manual", CheckCISLevel: "1"}}},
+		},
+	}
+
+	summary, err := c.RunGroup("1.9", "1.1", "1.1.2", "1.2.1", "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Warn != 3 {
+		t.Errorf("expected the matched groups to run, got %v", summary)
+	}
+	if expected := []string{"1.9", "1.2.1"}; !reflect.DeepEqual(c.UnmatchedGroupIDs, expected) {
+		t.Errorf("expected unmatched IDs %v, got %v", expected, c.UnmatchedGroupIDs)
+	}
+
+	if _, err := c.RunGroup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.UnmatchedGroupIDs != nil {
+		t.Errorf("expected no unmatched IDs when running every group, got %v", c.UnmatchedGroupIDs)
+	}
+}
+
+func TestControls_RunStream(t *testing.T) {
+	newControls := func() *Controls {
+		return &Controls{
+			UserCISLevel: "1",
+			Groups: []*Group{
+				{ID: "1.1", Checks: []*Check{
+					{ID: "1.1.1", Type: "manual", CheckCISLevel: "1"},
+					{ID: "1.1.2", Type: "skip", CheckCISLevel: "1"},
+				}},
+				{ID: "1.2", Checks: []*Check{{ID: "1.2.1", Type: "manual", CheckCISLevel: "1"}}},
+			},
+		}
+	}
+
+	c := newControls()
+	calls := 0
+	c.OnCheck = func(check *Check) { calls++ }
+	ids := []string{}
+	for check := range c.RunStream(context.Background()) {
+		if check.State == "" {
+			t.Errorf("check %s was sent before it ran", check.ID)
+		}
+		ids = append(ids, check.ID)
+	}
+	if exp := []string{"1.1.1", "1.1.2", "1.2.1"}; !reflect.DeepEqual(ids, exp) {
+		t.Errorf("expected checks %v, got %v", exp, ids)
+	}
+	if c.Summary != (Summary{Warn: 2, Info: 1}) || calls != 3 {
+		t.Errorf("unexpected summary %v after %d OnCheck calls", c.Summary, calls)
+	}
+
+	c = newControls()
+	for range c.RunStream(context.Background(), "1.9-1.1.1") {
+		t.Errorf("expected no checks for an invalid selection")
+	}
+	if len(c.Errors) != 1 || !strings.Contains(c.Errors[0].Error(), "invalid check range") {
+		t.Errorf("expected the selection error in Errors, got %v", c.Errors)
+	}
+
+	c = newControls()
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := c.RunStream(ctx)
+	<-stream
+	cancel()
+	for range stream {
+	}
+	if c.Executed() == 3 {
+		t.Errorf("expected the run to stop early")
+	}
+}
+
+func TestNewControlsDerivesLevel(t *testing.T) {
+	in := []byte(`---
+id: 2
+text: "Worker Node Security Configuration"
+type: "node"
+groups:
+- id: 2.1
+  text: "Kubelet"
+  checks:
+  - id: 2.1.1
+    text: "level wins"
+    type: "manual"
+    level: 1
+    profile: "Level 2 - Worker Node"
+    scored: false
+  - id: 2.1.2
+    text: "profile wins over scored"
+    type: "manual"
+    profile: "Level 2 - Worker Node"
+    scored: true
+  - id: 2.1.3
+    text: "scored"
+    type: "manual"
+    scored: true
+  - id: 2.1.4
+    text: "not scored"
+    type: "manual"
+  - id: 2.1.5
+    text: "profile without a level"
+    type: "manual"
+    profile: "Worker Node"
+    scored: true
+`)
+	c, err := NewControls(NODE, "1", in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := []string{"1", "2", "1", "2", "1"}
+	for i, check := range c.Groups[0].Checks {
+		if check.CheckCISLevel != exp[i] {
+			t.Errorf("%s: expected level %s, got %s", check.ID, exp[i], check.CheckCISLevel)
+		}
+	}
+
+	// Derived levels gate checks as levels given in the file do.
+	summary, err := c.RunGroup()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Skip != 2 {
+		t.Errorf("expected the level 2 checks to be skipped, got %v", summary)
+	}
+}
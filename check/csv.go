@@ -0,0 +1,70 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+)
+
+var csvHeader = []string{
+	"group_id",
+	"group_text",
+	"check_id",
+	"check_text",
+	"state",
+	"level",
+	"remediation",
+}
+
+// CSV encodes the results of last run to CSV, with a header row followed by
+// one row per check. Remediations are given for controls.Distribution.
+func (controls *Controls) CSV() ([]byte, error) {
+	var b bytes.Buffer
+	if err := controls.writeCSV(&b, flattenGroups(controls.Groups)); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// writeCSV writes the checks of groups to out as CSV does.
+func (controls *Controls) writeCSV(out io.Writer, groups []*Group) error {
+	w := csv.NewWriter(out)
+
+	if err := w.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		for _, check := range group.Checks {
+			err := w.Write([]string{
+				group.ID,
+				group.Text,
+				check.ID,
+				check.Text,
+				string(check.State),
+				check.CheckCISLevel,
+				check.RemediationFor(controls.Distribution),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,81 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+)
+
+func TestControls_CSV(t *testing.T) {
+	cases := []struct {
+		controls *Controls
+		exp      [][]string
+	}{
+		{
+			controls: &Controls{},
+			exp:      [][]string{csvHeader},
+		},
+		{
+			controls: &Controls{
+				Groups: []*Group{
+					{
+						ID:   "1.1",
+						Text: "API Server",
+						Checks: []*Check{
+							{
+								ID:            "1.1.1",
+								Text:          "Ensure that the --anonymous-auth argument is set to false",
+								State:         FAIL,
+								CheckCISLevel: "1",
+								Remediation:   "Edit the API server pod specification file,\nand set \"--anonymous-auth=false\".",
+							},
+						},
+					},
+				},
+			},
+			exp: [][]string{
+				csvHeader,
+				{
+					"1.1",
+					"API Server",
+					"1.1.1",
+					"Ensure that the --anonymous-auth argument is set to false",
+					"FAIL",
+					"1",
+					"Edit the API server pod specification file,\nand set \"--anonymous-auth=false\".",
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		out, err := c.controls.CSV()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		records, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+		if err != nil {
+			t.Fatalf("failed to read CSV output: %v", err)
+		}
+
+		if !reflect.DeepEqual(records, c.exp) {
+			t.Errorf("expected %q, got %q", c.exp, records)
+		}
+	}
+}
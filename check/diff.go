@@ -0,0 +1,105 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"sort"
+)
+
+// CheckDiff records the state of a check in two runs. Old is empty for
+// checks that were added and New is empty for checks that were removed.
+type CheckDiff struct {
+	ID  string `json:"test_number"`
+	Old State  `json:"old_status,omitempty"`
+	New State  `json:"new_status,omitempty"`
+}
+
+// RunDiff is the difference between the results of two runs.
+type RunDiff struct {
+	// NewlyFailed holds checks that are FAIL now but were not before.
+	NewlyFailed []CheckDiff `json:"newly_failed"`
+	// Fixed holds checks that went from FAIL to PASS.
+	Fixed []CheckDiff `json:"fixed"`
+	// Changed holds checks whose state changed in any other way.
+	Changed []CheckDiff `json:"changed"`
+	// Added holds checks that are only present in the new run.
+	Added []CheckDiff `json:"added"`
+	// Removed holds checks that are only present in the old run.
+	Removed []CheckDiff `json:"removed"`
+}
+
+// Diff compares the results of two runs. Each bucket of the returned
+// RunDiff is sorted by check ID.
+func Diff(old, new *Controls) *RunDiff {
+	d := &RunDiff{
+		NewlyFailed: []CheckDiff{},
+		Fixed:       []CheckDiff{},
+		Changed:     []CheckDiff{},
+		Added:       []CheckDiff{},
+		Removed:     []CheckDiff{},
+	}
+
+	oldStates := checkStates(old)
+	newStates := checkStates(new)
+
+	for id, n := range newStates {
+		o, ok := oldStates[id]
+		cd := CheckDiff{ID: id, Old: o, New: n}
+
+		switch {
+		case !ok:
+			d.Added = append(d.Added, cd)
+		case o == n:
+		case n == FAIL:
+			d.NewlyFailed = append(d.NewlyFailed, cd)
+		case o == FAIL && n == PASS:
+			d.Fixed = append(d.Fixed, cd)
+		default:
+			d.Changed = append(d.Changed, cd)
+		}
+	}
+
+	for id, o := range oldStates {
+		if _, ok := newStates[id]; !ok {
+			d.Removed = append(d.Removed, CheckDiff{ID: id, Old: o})
+		}
+	}
+
+	for _, b := range [][]CheckDiff{d.NewlyFailed, d.Fixed, d.Changed, d.Added, d.Removed} {
+		sortCheckDiffs(b)
+	}
+
+	return d
+}
+
+func checkStates(controls *Controls) map[string]State {
+	m := make(map[string]State)
+	if controls == nil {
+		return m
+	}
+
+	for _, group := range flattenGroups(controls.Groups) {
+		for _, check := range group.Checks {
+			m[check.ID] = check.State
+		}
+	}
+	return m
+}
+
+func sortCheckDiffs(d []CheckDiff) {
+	sort.Slice(d, func(i, j int) bool {
+		return CompareCheckIDs(d[i].ID, d[j].ID) < 0
+	})
+}
@@ -0,0 +1,72 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := &Controls{
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", State: PASS},
+					{ID: "1.1.2", State: FAIL},
+					{ID: "1.1.3", State: PASS},
+					{ID: "1.1.4", State: WARN},
+					{ID: "1.1.10", State: PASS},
+					{ID: "1.1.5", State: PASS},
+				},
+			},
+		},
+	}
+	new := &Controls{
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", State: PASS},
+					{ID: "1.1.2", State: PASS},
+					{ID: "1.1.3", State: FAIL},
+					{ID: "1.1.4", State: INFO},
+					{ID: "1.1.10", State: FAIL},
+				},
+			},
+			{
+				ID: "1.2",
+				Checks: []*Check{
+					{ID: "1.2.1", State: FAIL},
+				},
+			},
+		},
+	}
+
+	d := Diff(old, new)
+
+	exp := &RunDiff{
+		NewlyFailed: []CheckDiff{{"1.1.3", PASS, FAIL}, {"1.1.10", PASS, FAIL}},
+		Fixed:       []CheckDiff{{"1.1.2", FAIL, PASS}},
+		Changed:     []CheckDiff{{"1.1.4", WARN, INFO}},
+		Added:       []CheckDiff{{"1.2.1", "", FAIL}},
+		Removed:     []CheckDiff{{"1.1.5", PASS, ""}},
+	}
+
+	if !reflect.DeepEqual(d, exp) {
+		t.Errorf("expected %+v, got %+v", exp, d)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidYAML matches, with errors.Is, the errors returned for
+	// controls files that cannot be parsed.
+	ErrInvalidYAML = errors.New("invalid YAML")
+
+	// ErrNodeTypeMismatch matches, with errors.Is, the errors returned for
+	// controls files of another node type than the one expected.
+	ErrNodeTypeMismatch = errors.New("node type mismatch")
+
+	// ErrNoChecksMatched matches, with errors.Is, the errors returned by
+	// runs with StrictSelection whose IDs selected no checks.
+	ErrNoChecksMatched = errors.New("no checks matched the selection")
+)
+
+// InvalidYAMLError is returned when a controls file cannot be parsed.
+type InvalidYAMLError struct {
+	Err error
+}
+
+func (e *InvalidYAMLError) Error() string {
+	return fmt.Sprintf("failed to unmarshal YAML: %s", e.Err)
+}
+
+// Unwrap returns the error of the YAML parser.
+func (e *InvalidYAMLError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrInvalidYAML.
+func (e *InvalidYAMLError) Is(target error) bool {
+	return target == ErrInvalidYAML
+}
+
+// NodeTypeMismatchError is returned when a controls file is not of the node
+// type expected.
+type NodeTypeMismatchError struct {
+	Expected NodeType
+	Actual   NodeType
+}
+
+func (e *NodeTypeMismatchError) Error() string {
+	return fmt.Sprintf("non-%s controls file specified", e.Expected)
+}
+
+// Is reports whether target is ErrNodeTypeMismatch.
+func (e *NodeTypeMismatchError) Is(target error) bool {
+	return target == ErrNodeTypeMismatch
+}
@@ -0,0 +1,41 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewControlsErrors(t *testing.T) {
+	_, err := NewControls(MASTER, "1", []byte("id: [1\n"))
+	if !errors.Is(err, ErrInvalidYAML) || errors.Is(err, ErrNodeTypeMismatch) {
+		t.Errorf("expected an invalid YAML error, got %v", err)
+	}
+
+	node := []byte("---\nid: 2\ntype: \"node\"\ngroups: []\n")
+	_, err = NewControlsFromFiles(MASTER, "1", node)
+	if !errors.Is(err, ErrNodeTypeMismatch) || errors.Is(err, ErrInvalidYAML) {
+		t.Errorf("expected a node type mismatch, got %v", err)
+	}
+
+	var mismatch *NodeTypeMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Expected != MASTER || mismatch.Actual != NODE {
+		t.Errorf("expected the node types in the error, got %+v", mismatch)
+	}
+	if exp := "controls file 1: non-master controls file specified"; err.Error() != exp {
+		t.Errorf("expected message %q, got %q", exp, err.Error())
+	}
+}
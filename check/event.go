@@ -0,0 +1,53 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// checkEvent is the payload of Event. Its fields are kept few and stable
+// for consumers such as webhooks.
+type checkEvent struct {
+	ID        string   `json:"id"`
+	Text      string   `json:"text"`
+	State     State    `json:"state"`
+	NodeType  NodeType `json:"node_type"`
+	Level     string   `json:"level"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// Event encodes the result of the check as a small JSON object of its id,
+// text, state, node type, level and the time it completed, in RFC 3339 UTC,
+// suitable for posting to a webhook from Controls.OnCheck.
+// The node type is that of the controls that ran the check, and is empty
+// for checks run on their own. The time is that of the call if the check has
+// not completed.
+func (c *Check) Event() ([]byte, error) {
+	ts := c.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	return json.Marshal(checkEvent{
+		ID:        c.ID,
+		Text:      c.Text,
+		State:     c.State,
+		NodeType:  c.nodeType,
+		Level:     c.CheckCISLevel,
+		Timestamp: ts.UTC().Format(time.RFC3339),
+	})
+}
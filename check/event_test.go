@@ -0,0 +1,70 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCheck_Event(t *testing.T) {
+	events := [][]byte{}
+	c := &Controls{
+		Type:         MASTER,
+		UserCISLevel: "1",
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", Text: "manual check", Type: "manual", CheckCISLevel: "1"},
+					{ID: "1.1.2", Text: "level 2 check", Type: "manual", CheckCISLevel: "2"},
+				},
+			},
+		},
+	}
+	c.OnCheck = func(check *Check) {
+		b, err := check.Event()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		events = append(events, b)
+	}
+
+	before := time.Now().Add(-time.Second)
+	if _, err := c.RunGroup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected an event per check, got %d", len(events))
+	}
+
+	var e map[string]string
+	if err := json.Unmarshal(events[0], &e); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(e) != 6 || e["id"] != "1.1.1" || e["text"] != "manual check" || e["state"] != "WARN" ||
+		e["node_type"] != "master" || e["level"] != "1" {
+		t.Errorf("unexpected event: %s", events[0])
+	}
+	ts, err := time.Parse(time.RFC3339, e["timestamp"])
+	if err != nil || ts.Before(before.Truncate(time.Second)) {
+		t.Errorf("expected the completion time, got %q (%v)", e["timestamp"], err)
+	}
+
+	if err := json.Unmarshal(events[1], &e); err != nil || e["state"] != "SKIP" {
+		t.Errorf("expected an event for the skipped check, got %s", events[1])
+	}
+}
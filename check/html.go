@@ -0,0 +1,108 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"html/template"
+)
+
+// htmlReport is cloned to give remediation the distribution of the
+// controls run.
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"remediation": func(check *Check) string { return check.Remediation },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Text}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+summary { cursor: pointer; font-weight: bold; padding: 4px 0; }
+.PASS { background: #dff0d8; }
+.FAIL { background: #f2dede; }
+.WARN { background: #fcf8e3; }
+.INFO { background: #d9edf7; }
+.SKIP { background: #eeeeee; }
+.NA { background: #eeeeee; }
+.ERROR { background: #f2dede; }
+pre { white-space: pre-wrap; margin: 0; }
+</style>
+</head>
+<body>
+<h1>{{.ID}} {{.Text}}</h1>
+<p>Version: {{.Version}}</p>
+<table>
+<tr><th>PASS</th><th>FAIL</th><th>WARN</th><th>INFO</th><th>SKIP</th><th>NA</th><th>ERROR</th></tr>
+<tr><td>{{.Summary.Pass}}</td><td>{{.Summary.Fail}}</td><td>{{.Summary.Warn}}</td><td>{{.Summary.Info}}</td><td>{{.Summary.Skip}}</td><td>{{.Summary.NA}}</td><td>{{.Summary.Error}}</td></tr>
+</table>
+{{range .Groups}}{{template "group" .}}{{end}}
+</body>
+</html>
+{{define "group"}}
+<details>
+<summary>{{.ID}} {{.Text}} (pass: {{.Pass}}, fail: {{.Fail}}, warn: {{.Warn}}, info: {{.Info}}, skip: {{.Skip}}, na: {{.NA}}, error: {{.Error}})</summary>
+{{if .Checks}}<table>
+<tr><th>ID</th><th>Description</th><th>State</th><th>Remediation</th></tr>
+{{range .Checks}}<tr class="{{.State}}"><td>{{.ID}}</td><td>{{.Text}}</td><td>{{.State}}</td><td><pre>{{remediation .}}</pre></td></tr>
+{{end}}</table>
+{{end}}{{range .SubGroups}}{{template "group" .}}{{end}}</details>
+{{end}}`))
+
+// HTML renders the results of last run as a self-contained HTML page with
+// a collapsible section for each group, holding those of its subgroups.
+// PASS and SKIP checks are left out if HidePassed and HideSkipped are set,
+// and remediations are given for controls.Distribution.
+func (controls *Controls) HTML() ([]byte, error) {
+	c := *controls
+	c.Groups = controls.shownGroups(controls.Groups)
+
+	t, err := htmlReport.Clone()
+	if err != nil {
+		return nil, err
+	}
+	t.Funcs(template.FuncMap{
+		"remediation": func(check *Check) string { return check.RemediationFor(controls.Distribution) },
+	})
+
+	var b bytes.Buffer
+	if err := t.Execute(&b, &c); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// shownGroups returns copies of groups and their subgroups holding only the
+// checks that controls.hides does not leave out, with the counts of the
+// groups unchanged.
+func (controls *Controls) shownGroups(groups []*Group) []*Group {
+	shown := make([]*Group, len(groups))
+	for i, group := range groups {
+		g := *group
+		g.Checks = []*Check{}
+		for _, check := range group.Checks {
+			if !controls.hides(check) {
+				g.Checks = append(g.Checks, check)
+			}
+		}
+		if group.SubGroups != nil {
+			g.SubGroups = controls.shownGroups(group.SubGroups)
+		}
+		shown[i] = &g
+	}
+	return shown
+}
@@ -0,0 +1,62 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestControls_HTML(t *testing.T) {
+	c := &Controls{
+		ID:      "1",
+		Version: "1.13",
+		Text:    "Master Node Security Configuration",
+		Groups: []*Group{
+			{
+				ID:   "1.1",
+				Text: "API Server",
+				Fail: 1,
+				Checks: []*Check{
+					{ID: "1.1.1", Text: "Ensure <script>alert(1)</script> is escaped", State: FAIL},
+				},
+			},
+		},
+		Summary: Summary{Fail: 1},
+	}
+
+	out, err := c.HTML()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	html := string(out)
+	for _, s := range []string{
+		"Master Node Security Configuration",
+		"Version: 1.13",
+		"<details>",
+		"1.1 API Server (pass: 0, fail: 1",
+		`<tr class="FAIL">`,
+		"&lt;script&gt;",
+	} {
+		if !strings.Contains(html, s) {
+			t.Errorf("expected output to contain %q", s)
+		}
+	}
+
+	if strings.Contains(html, "<script>") {
+		t.Errorf("check text was not escaped")
+	}
+}
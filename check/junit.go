@@ -0,0 +1,116 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	ID        string          `xml:"id,attr"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// JUnit encodes the results of last run to JUnit XML. Each group is a
+// testsuite and each check a testcase; FAIL and WARN checks are reported
+// as failures, ERROR checks as errors and SKIP and NA checks as skipped.
+func (controls *Controls) JUnit() ([]byte, error) {
+	suites := junitTestSuites{
+		Name:     controls.Text,
+		Tests:    controls.Pass + controls.Fail + controls.Warn + controls.Info + controls.Skip + controls.NA + controls.Error,
+		Failures: controls.Fail + controls.Warn,
+		Errors:   controls.Error,
+		Skipped:  controls.Skip + controls.NA,
+	}
+
+	for _, group := range flattenGroups(controls.Groups) {
+		suite := junitTestSuite{
+			ID:   group.ID,
+			Name: group.Text,
+		}
+
+		for _, check := range group.Checks {
+			tc := junitTestCase{
+				Name:      check.Text,
+				ClassName: check.ID,
+			}
+
+			switch check.State {
+			case FAIL, WARN:
+				tc.Failure = &junitFailure{
+					Message: string(check.State),
+					Type:    string(check.State),
+					Body:    check.RemediationFor(controls.Distribution),
+				}
+				suite.Failures++
+			case ERROR:
+				tc.Error = &junitFailure{
+					Message: strings.Join(check.TestInfo, "\n"),
+					Type:    string(check.State),
+				}
+				suite.Errors++
+			case SKIP, NA:
+				tc.Skipped = &junitSkipped{}
+				suite.Skipped++
+			}
+
+			suite.Tests++
+			suite.TestCases = append(suite.TestCases, tc)
+		}
+
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	out, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), out...), nil
+}
@@ -0,0 +1,75 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestControls_JUnit(t *testing.T) {
+	c := &Controls{
+		Text: "Master Node Security Configuration",
+		Groups: []*Group{
+			{
+				ID:   "1.1",
+				Text: "API Server",
+				Checks: []*Check{
+					{ID: "1.1.1", Text: "passing check", State: PASS},
+					{ID: "1.1.2", Text: "failing check", State: FAIL, Remediation: "fix it"},
+					{ID: "1.1.3", Text: "warning check", State: WARN},
+					{ID: "1.1.4", Text: "skipped check", State: SKIP},
+				},
+			},
+		},
+		Summary: Summary{Pass: 1, Fail: 1, Warn: 1, Skip: 1},
+	}
+
+	out, err := c.JUnit()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(out, &suites); err != nil {
+		t.Fatalf("failed to unmarshal JUnit output: %v", err)
+	}
+
+	if suites.Tests != 4 || suites.Failures != 2 || suites.Skipped != 1 {
+		t.Errorf("unexpected testsuites counts: %+v", suites)
+	}
+
+	if len(suites.Suites) != 1 {
+		t.Fatalf("expected 1 testsuite, got %d", len(suites.Suites))
+	}
+
+	suite := suites.Suites[0]
+	if suite.Name != "API Server" || suite.Tests != 4 || suite.Failures != 2 || suite.Skipped != 1 {
+		t.Errorf("unexpected testsuite: %+v", suite)
+	}
+
+	failed := suite.TestCases[1]
+	if failed.Name != "failing check" || failed.Failure == nil || failed.Failure.Body != "fix it" {
+		t.Errorf("unexpected failing testcase: %+v", failed)
+	}
+
+	if suite.TestCases[0].Failure != nil || suite.TestCases[0].Skipped != nil {
+		t.Errorf("passing testcase should have no failure or skipped element")
+	}
+
+	if suite.TestCases[3].Skipped == nil {
+		t.Errorf("skipped testcase should have a skipped element")
+	}
+}
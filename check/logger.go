@@ -0,0 +1,124 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+)
+
+// Logger receives the diagnostics of loading and running controls, such as
+// the checks skipped and the audit commands that failed, for programs that
+// embed kube-bench to route them to their own logs. Each message comes with
+// alternating keys and values, such as "check", "1.1.1".
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+var (
+	loggerMu      sync.RWMutex
+	packageLogger Logger = glogLogger{}
+)
+
+// SetLogger sets the logger used by NewControls, Check.Run and the controls
+// whose Logger is not set. By default messages are written to glog at
+// verbosity level 2, as kube-bench always has, so that they are only shown
+// with -v=2. A nil l restores the default, and NopLogger discards them.
+func SetLogger(l Logger) {
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	if l == nil {
+		l = glogLogger{}
+	}
+	packageLogger = l
+}
+
+func currentLogger() Logger {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+	return packageLogger
+}
+
+// logger returns controls.Logger, or the logger set with SetLogger.
+func (controls *Controls) logger() Logger {
+	if controls.Logger != nil {
+		return controls.Logger
+	}
+	return currentLogger()
+}
+
+// NopLogger returns a Logger that discards every message.
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Warn(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Error(msg string, keysAndValues ...interface{}) {}
+
+// glogLogger writes messages to glog at verbosity level 2, prefixed with
+// their level and followed by their keys and values.
+type glogLogger struct{}
+
+func (glogLogger) Debug(msg string, keysAndValues ...interface{}) {
+	glogMessage("DEBUG", msg, keysAndValues)
+}
+
+func (glogLogger) Info(msg string, keysAndValues ...interface{}) {
+	glogMessage("INFO", msg, keysAndValues)
+}
+
+func (glogLogger) Warn(msg string, keysAndValues ...interface{}) {
+	glogMessage("WARN", msg, keysAndValues)
+}
+
+func (glogLogger) Error(msg string, keysAndValues ...interface{}) {
+	glogMessage("ERROR", msg, keysAndValues)
+}
+
+func glogMessage(level, msg string, keysAndValues []interface{}) {
+	if glog.V(2) {
+		glog.Info(formatMessage(level, msg, keysAndValues))
+	}
+}
+
+// formatMessage formats a message as "LEVEL msg key=value ...", quoting
+// values that hold spaces. A key without a value is given "(MISSING)".
+func formatMessage(level, msg string, keysAndValues []interface{}) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s", level, msg)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		var v interface{} = "(MISSING)"
+		if i+1 < len(keysAndValues) {
+			v = keysAndValues[i+1]
+		}
+		s := fmt.Sprint(v)
+		if strings.ContainsAny(s, " \t\n\"") {
+			s = fmt.Sprintf("%q", s)
+		}
+		fmt.Fprintf(&b, " %v=%s", keysAndValues[i], s)
+	}
+	return b.String()
+}
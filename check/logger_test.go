@@ -0,0 +1,117 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) log(level, msg string, keysAndValues []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, formatMessage(level, msg, keysAndValues))
+}
+
+func (l *recordingLogger) Debug(msg string, kv ...interface{}) { l.log("DEBUG", msg, kv) }
+func (l *recordingLogger) Info(msg string, kv ...interface{})  { l.log("INFO", msg, kv) }
+func (l *recordingLogger) Warn(msg string, kv ...interface{})  { l.log("WARN", msg, kv) }
+func (l *recordingLogger) Error(msg string, kv ...interface{}) { l.log("ERROR", msg, kv) }
+
+func (l *recordingLogger) has(prefix string) bool {
+	for _, m := range l.messages {
+		if strings.HasPrefix(m, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestControls_Logger(t *testing.T) {
+	pkg := &recordingLogger{}
+	SetLogger(pkg)
+	defer SetLogger(nil)
+
+	c, err := NewControls(MASTER, "1", []byte(`---
+controls:
+id: 1
+type: "master"
+groups:
+- id: 1.1
+  checks:
+  - id: 1.1.1
+    pipeline:
+    - [no-such-command, --flag]
+    scored: true
+    level: 1
+  - id: 1.1.2
+    type: "manual"
+    level: 2
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !pkg.has("DEBUG loaded controls id=1 type=master version= checks=2") {
+		t.Errorf("expected NewControls to log to the package logger, got %q", pkg.messages)
+	}
+
+	l := &recordingLogger{}
+	c.Logger = l
+	if _, err := c.RunGroup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, exp := range []string{
+		`DEBUG skipping check check=1.1.2 reason="requires CIS level 2"`,
+		"WARN pipeline command not found check=1.1.1 stage=1 command=no-such-command",
+		`INFO ran checks type=master summary="PASS=0 FAIL=0 WARN=0 INFO=0 SKIP=1 NA=0 ERROR=1" errors=0`,
+	} {
+		if !l.has(exp) {
+			t.Errorf("expected %q to be logged, got %q", exp, l.messages)
+		}
+	}
+	if len(pkg.messages) != 1 {
+		t.Errorf("expected runs to log only to the logger of the controls, got %q", pkg.messages)
+	}
+
+	// Discarding messages leaves the results unchanged.
+	c.Logger = NopLogger()
+	if summary, err := c.RunGroup(); err != nil || summary.Error != 1 {
+		t.Errorf("unexpected result with NopLogger: %v, %v", summary, err)
+	}
+}
+
+func TestFormatMessage(t *testing.T) {
+	cases := []struct {
+		kv  []interface{}
+		exp string
+	}{
+		{nil, "WARN audit failed"},
+		{[]interface{}{"check", "1.1.1", "stage", 2}, "WARN audit failed check=1.1.1 stage=2"},
+		{[]interface{}{"error", fmt.Errorf("exit status 1")}, `WARN audit failed error="exit status 1"`},
+		{[]interface{}{"check"}, "WARN audit failed check=(MISSING)"},
+	}
+	for _, c := range cases {
+		if got := formatMessage("WARN", "audit failed", c.kv); got != c.exp {
+			t.Errorf("expected %q, got %q", c.exp, got)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+var mdCellEscaper = strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ")
+
+// Markdown renders the results of last run as GitHub flavored markdown, with
+// a summary table and a table of checks for each group. The remediation of
+// each failed check follows its group's table as a blockquote. PASS and SKIP
+// checks are left out of the tables if HidePassed and HideSkipped are set.
+func (controls *Controls) Markdown() ([]byte, error) {
+	var b bytes.Buffer
+	controls.writeMarkdown(&b, flattenGroups(controls.Groups))
+	return b.Bytes(), nil
+}
+
+// writeMarkdown writes the markdown of Markdown for groups to b.
+func (controls *Controls) writeMarkdown(b *bytes.Buffer, groups []*Group) {
+	fmt.Fprintf(b, "# %s %s\n\n", controls.ID, controls.Text)
+	if controls.Version != "" {
+		fmt.Fprintf(b, "Version: %s\n\n", controls.Version)
+	}
+
+	fmt.Fprintf(b, "## Summary\n\n")
+	fmt.Fprintf(b, "| PASS | FAIL | WARN | INFO | SKIP | NA | ERROR |\n")
+	fmt.Fprintf(b, "|------|------|------|------|------|----|-------|\n")
+	fmt.Fprintf(b, "| %d | %d | %d | %d | %d | %d | %d |\n",
+		controls.Pass, controls.Fail, controls.Warn, controls.Info, controls.Skip, controls.NA, controls.Error,
+	)
+
+	for _, group := range groups {
+		fmt.Fprintf(b, "\n## %s %s\n\n", group.ID, group.Text)
+		fmt.Fprintf(b, "| ID | Description | State |\n")
+		fmt.Fprintf(b, "|----|-------------|-------|\n")
+
+		failed := []*Check{}
+		for _, check := range group.Checks {
+			if controls.hides(check) {
+				continue
+			}
+			fmt.Fprintf(b, "| %s | %s | %s |\n",
+				mdCellEscaper.Replace(check.ID), mdCellEscaper.Replace(check.Text), check.State,
+			)
+			if check.State == FAIL {
+				failed = append(failed, check)
+			}
+		}
+
+		for _, check := range failed {
+			fmt.Fprintf(b, "\n**%s remediation:**\n\n", check.ID)
+			for _, l := range strings.Split(strings.TrimRight(check.RemediationFor(controls.Distribution), "\n"), "\n") {
+				fmt.Fprintf(b, "> %s\n", l)
+			}
+		}
+	}
+}
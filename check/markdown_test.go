@@ -0,0 +1,67 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+)
+
+func TestControls_Markdown(t *testing.T) {
+	c := &Controls{
+		ID:   "1",
+		Text: "Master Node Security Configuration",
+		Groups: []*Group{
+			{
+				ID:   "1.1",
+				Text: "API Server",
+				Checks: []*Check{
+					{ID: "1.1.1", Text: "passing check", State: PASS},
+					{ID: "1.1.2", Text: "check with a | pipe", State: FAIL, Remediation: "Edit the file\nand set --flag=false\n"},
+				},
+			},
+		},
+		Summary: Summary{Pass: 1, Fail: 1},
+	}
+
+	out, err := c.Markdown()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := `# 1 Master Node Security Configuration
+
+## Summary
+
+| PASS | FAIL | WARN | INFO | SKIP | NA | ERROR |
+|------|------|------|------|------|----|-------|
+| 1 | 1 | 0 | 0 | 0 | 0 | 0 |
+
+## 1.1 API Server
+
+| ID | Description | State |
+|----|-------------|-------|
+| 1.1.1 | passing check | PASS |
+| 1.1.2 | check with a \| pipe | FAIL |
+
+**1.1.2 remediation:**
+
+> Edit the file
+> and set --flag=false
+`
+
+	if string(out) != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, out)
+	}
+}
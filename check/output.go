@@ -0,0 +1,89 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Format is an output format of the results of a run.
+type Format string
+
+const (
+	// FormatJSON as written by WriteJSON.
+	FormatJSON Format = "json"
+	// FormatCSV as encoded by CSV.
+	FormatCSV Format = "csv"
+	// FormatTable as written by WriteTable.
+	FormatTable Format = "table"
+	// FormatMarkdown as rendered by Markdown.
+	FormatMarkdown Format = "markdown"
+)
+
+// Formats returns the formats WriteAll can write, in the order it writes
+// them.
+func Formats() []Format {
+	return []Format{FormatJSON, FormatCSV, FormatTable, FormatMarkdown}
+}
+
+// WriteAll writes the results of last run in each format of outputs to the
+// writer it maps to, for example JSON to a file and a table to the build log.
+// The formats that list checks group by group share a single walk of the
+// groups, and formats are written in the order of Formats. An unknown format
+// is reported before anything is written.
+func (controls *Controls) WriteAll(outputs map[Format]io.Writer) error {
+	for f := range outputs {
+		if !isFormat(f) {
+			return fmt.Errorf("unknown output format %q", f)
+		}
+	}
+
+	groups := flattenGroups(controls.Groups)
+	for _, f := range Formats() {
+		w, ok := outputs[f]
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch f {
+		case FormatJSON:
+			err = controls.WriteJSON(w)
+		case FormatCSV:
+			err = controls.writeCSV(w, groups)
+		case FormatTable:
+			err = controls.writeTable(w, groups)
+		case FormatMarkdown:
+			var b bytes.Buffer
+			controls.writeMarkdown(&b, groups)
+			_, err = b.WriteTo(w)
+		}
+		if err != nil {
+			return fmt.Errorf("writing %s output: %w", f, err)
+		}
+	}
+	return nil
+}
+
+func isFormat(f Format) bool {
+	for _, known := range Formats() {
+		if f == known {
+			return true
+		}
+	}
+	return false
+}
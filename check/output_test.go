@@ -0,0 +1,135 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestControls_WriteAll(t *testing.T) {
+	c := &Controls{
+		ID:           "1",
+		Text:         "Master Node Security Configuration",
+		UserCISLevel: "1",
+		NoColor:      true,
+		Groups: []*Group{
+			{ID: "1.1", Text: "API Server", Checks: []*Check{
+				{ID: "1.1.1", Text: "manual check", Type: "manual", CheckCISLevel: "1"},
+				{ID: "1.1.2", Text: "skipped check", Type: "skip", CheckCISLevel: "1"},
+			}},
+		},
+	}
+	if _, err := c.RunGroup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var js, csv, table, md bytes.Buffer
+	err := c.WriteAll(map[Format]io.Writer{
+		FormatJSON:     &js,
+		FormatCSV:      &csv,
+		FormatTable:    &table,
+		FormatMarkdown: &md,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedJSON, _ := c.JSON()
+	expectedCSV, _ := c.CSV()
+	var expectedTable bytes.Buffer
+	c.WriteTable(&expectedTable)
+	expectedMD, _ := c.Markdown()
+
+	for _, o := range []struct {
+		f        Format
+		got      []byte
+		expected []byte
+	}{
+		{FormatJSON, js.Bytes(), expectedJSON},
+		{FormatCSV, csv.Bytes(), expectedCSV},
+		{FormatTable, table.Bytes(), expectedTable.Bytes()},
+		{FormatMarkdown, md.Bytes(), expectedMD},
+	} {
+		if !bytes.Equal(o.got, o.expected) {
+			t.Errorf("%s: expected\n%s\ngot\n%s", o.f, o.expected, o.got)
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestControls_WriteAllErrors(t *testing.T) {
+	c := &Controls{ID: "1"}
+
+	var b bytes.Buffer
+	err := c.WriteAll(map[Format]io.Writer{FormatJSON: &b, "xml": &b})
+	if err == nil || err.Error() != `unknown output format "xml"` {
+		t.Errorf("expected an unknown format error, got %v", err)
+	}
+	if b.Len() != 0 {
+		t.Errorf("expected nothing to be written, got %q", b.String())
+	}
+
+	err = c.WriteAll(map[Format]io.Writer{FormatCSV: failingWriter{}})
+	if err == nil || err.Error() != "writing csv output: disk full" {
+		t.Errorf("expected the write error, got %v", err)
+	}
+}
+
+func TestControls_ReportsRemediationFor(t *testing.T) {
+	c := &Controls{
+		Distribution: "kubeadm",
+		Groups: []*Group{
+			{ID: "1.1", Text: "API Server", Checks: []*Check{
+				{
+					ID:           "1.1.1",
+					Text:         "failing check",
+					State:        FAIL,
+					Remediations: map[string]string{"kubeadm": "kubeadm steps", "systemd": "systemd steps"},
+				},
+			}},
+		},
+		Summary: Summary{Fail: 1},
+	}
+
+	for kind, controls := range map[string]*Controls{
+		"Controls": c,
+		"Filter":   c.Filter(FAIL),
+		"Compact":  c.Compact(),
+	} {
+		for name, report := range map[string]func() ([]byte, error){
+			"CSV":      controls.CSV,
+			"JUnit":    controls.JUnit,
+			"HTML":     controls.HTML,
+			"Markdown": controls.Markdown,
+			"SARIF":    controls.SARIF,
+		} {
+			out, err := report()
+			if err != nil {
+				t.Fatalf("%s %s: unexpected error: %v", kind, name, err)
+			}
+			if !bytes.Contains(out, []byte("kubeadm steps")) || bytes.Contains(out, []byte("systemd steps")) {
+				t.Errorf("%s %s: expected the kubeadm remediation only, got %s", kind, name, out)
+			}
+		}
+	}
+}
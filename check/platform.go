@@ -0,0 +1,33 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import "strings"
+
+// platformSkipReason returns why check does not apply to controls.Platform,
+// or "" if it applies. Checks without platforms apply everywhere, as do all
+// checks when controls.Platform is not set. Platforms are compared without
+// regard to case, so that "EKS" matches "eks".
+func (controls *Controls) platformSkipReason(check *Check) string {
+	if controls.Platform == "" || len(check.Platforms) == 0 {
+		return ""
+	}
+	for _, p := range check.Platforms {
+		if strings.EqualFold(p, controls.Platform) {
+			return ""
+		}
+	}
+	return "not applicable on " + controls.Platform
+}
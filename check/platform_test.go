@@ -0,0 +1,74 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import "testing"
+
+func TestControls_RunGroupPlatforms(t *testing.T) {
+	newControls := func(platform string) *Controls {
+		return &Controls{
+			UserCISLevel: "1",
+			Platform:     platform,
+			Groups: []*Group{
+				{ID: "1.1", Checks: []*Check{
+					{ID: "1.1.1", Type: "manual", CheckCISLevel: "1"},
+					{ID: "1.1.2", Type: "manual", CheckCISLevel: "1", Platforms: []string{"eks", "gke"}},
+					{ID: "1.1.3", Type: "manual", CheckCISLevel: "1", Platforms: []string{"openshift"}},
+				}},
+			},
+		}
+	}
+
+	cases := []struct {
+		platform string
+		skipped  []string
+	}{
+		{"", nil},
+		{"EKS", []string{"1.1.3"}},
+		{"openshift", []string{"1.1.2"}},
+		{"aks", []string{"1.1.2", "1.1.3"}},
+	}
+
+	for _, c := range cases {
+		for _, run := range []func(*Controls) (Summary, error){
+			func(cs *Controls) (Summary, error) { return cs.RunGroup() },
+			func(cs *Controls) (Summary, error) { return cs.RunChecks() },
+		} {
+			cs := newControls(c.platform)
+			if _, err := run(cs); err != nil {
+				t.Fatalf("%q: unexpected error: %v", c.platform, err)
+			}
+
+			skipped := []string{}
+			cs.EachCheck(func(group *Group, check *Check) {
+				if check.State == SKIP {
+					skipped = append(skipped, check.ID)
+					if check.SkipReason != "not applicable on "+c.platform {
+						t.Errorf("%q: unexpected skip reason %q", c.platform, check.SkipReason)
+					}
+				}
+			})
+			if len(skipped) != len(c.skipped) {
+				t.Errorf("%q: expected %v to be skipped, got %v", c.platform, c.skipped, skipped)
+				continue
+			}
+			for i := range skipped {
+				if skipped[i] != c.skipped[i] {
+					t.Errorf("%q: expected %v to be skipped, got %v", c.platform, c.skipped, skipped)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// promStateValues is the value reported by kube_bench_check_state for each
+// check state.
+var promStateValues = map[State]int{
+	PASS:  0,
+	FAIL:  1,
+	WARN:  2,
+	INFO:  3,
+	SKIP:  4,
+	NA:    5,
+	ERROR: 6,
+}
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Prometheus encodes the results of last run in the Prometheus text
+// exposition format, suitable for the node_exporter textfile collector.
+func (controls *Controls) Prometheus() ([]byte, error) {
+	var b bytes.Buffer
+
+	nodeType := promLabelEscaper.Replace(string(controls.Type))
+	version := promLabelEscaper.Replace(controls.Version)
+
+	fmt.Fprintln(&b, "# HELP kube_bench_summary_total Number of checks in each state.")
+	fmt.Fprintln(&b, "# TYPE kube_bench_summary_total gauge")
+	writePromSummary(&b, "kube_bench_summary_total",
+		fmt.Sprintf(`node_type="%s",version="%s"`, nodeType, version),
+		controls.Summary,
+	)
+
+	fmt.Fprintln(&b, "# HELP kube_bench_checks_total Number of checks in each state by CIS level.")
+	fmt.Fprintln(&b, "# TYPE kube_bench_checks_total gauge")
+	for _, ls := range controls.LevelSummaries() {
+		writePromSummary(&b, "kube_bench_checks_total",
+			fmt.Sprintf(`node_type="%s",version="%s",level="%s"`, nodeType, version, promLabelEscaper.Replace(ls.Level)),
+			ls.Summary,
+		)
+	}
+
+	fmt.Fprintln(&b, "# HELP kube_bench_check_state State of each check (0=PASS, 1=FAIL, 2=WARN, 3=INFO, 4=SKIP, 5=NA, 6=ERROR).")
+	fmt.Fprintln(&b, "# TYPE kube_bench_check_state gauge")
+	for _, group := range flattenGroups(controls.Groups) {
+		for _, check := range group.Checks {
+			v, ok := promStateValues[check.State]
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(&b, "kube_bench_check_state{id=\"%s\",node_type=\"%s\",version=\"%s\",level=\"%s\"} %d\n",
+				promLabelEscaper.Replace(check.ID), nodeType, version, promLabelEscaper.Replace(check.CheckCISLevel), v,
+			)
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+func writePromSummary(b *bytes.Buffer, name, labels string, s Summary) {
+	fmt.Fprintf(b, "%s{%s,state=\"pass\"} %d\n", name, labels, s.Pass)
+	fmt.Fprintf(b, "%s{%s,state=\"fail\"} %d\n", name, labels, s.Fail)
+	fmt.Fprintf(b, "%s{%s,state=\"warn\"} %d\n", name, labels, s.Warn)
+	fmt.Fprintf(b, "%s{%s,state=\"info\"} %d\n", name, labels, s.Info)
+	fmt.Fprintf(b, "%s{%s,state=\"skip\"} %d\n", name, labels, s.Skip)
+	fmt.Fprintf(b, "%s{%s,state=\"na\"} %d\n", name, labels, s.NA)
+	fmt.Fprintf(b, "%s{%s,state=\"error\"} %d\n", name, labels, s.Error)
+}
@@ -0,0 +1,80 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestControls_Prometheus(t *testing.T) {
+	c := &Controls{
+		Version: "1.13",
+		Type:    MASTER,
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", State: PASS, CheckCISLevel: "1"},
+					{ID: "1.1.2", State: FAIL, CheckCISLevel: "2"},
+					{ID: "1.1.3", State: NA, CheckCISLevel: "10"},
+				},
+			},
+		},
+		Summary: Summary{Pass: 1, Fail: 1, NA: 1},
+		SummaryLevelWise: map[string]*Summary{
+			"1":  {Pass: 1},
+			"2":  {Fail: 1},
+			"10": {NA: 1},
+		},
+	}
+
+	out, err := c.Prometheus()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		`kube_bench_summary_total{node_type="master",version="1.13",state="fail"} 1`,
+		`kube_bench_summary_total{node_type="master",version="1.13",state="na"} 1`,
+		`kube_bench_checks_total{node_type="master",version="1.13",level="10",state="na"} 1`,
+		`kube_bench_checks_total{node_type="master",version="1.13",level="1",state="pass"} 1`,
+		`kube_bench_checks_total{node_type="master",version="1.13",level="2",state="fail"} 1`,
+		`kube_bench_checks_total{node_type="master",version="1.13",level="2",state="pass"} 0`,
+		`kube_bench_check_state{id="1.1.1",node_type="master",version="1.13",level="1"} 0`,
+		`kube_bench_check_state{id="1.1.2",node_type="master",version="1.13",level="2"} 1`,
+	}
+
+	lines := strings.Split(string(out), "\n")
+	for _, e := range expected {
+		found := false
+		for _, l := range lines {
+			if l == e {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("missing metric line %q in output:\n%s", e, out)
+		}
+	}
+
+	// Levels are ordered numerically.
+	l2 := strings.Index(string(out), `level="2"`)
+	l10 := strings.Index(string(out), `level="10"`)
+	if l2 < 0 || l10 < l2 {
+		t.Errorf("expected level 2 before level 10 in output:\n%s", out)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import "encoding/json"
+
+// Report holds the results of a whole cluster, such as those of master,
+// node and etcd controls, with their summaries summed.
+type Report struct {
+	Controls []*Controls `json:"controls"`
+	Summary  Summary     `json:"summary"`
+}
+
+// NewReport returns the report of the last run of each of cs, whose
+// Summary sums their summaries as MergeControls does.
+func NewReport(cs ...*Controls) *Report {
+	return &Report{Controls: cs, Summary: MergeControls(cs...)}
+}
+
+// SummaryOf returns the summary of the controls of node type t in the
+// report, summed if the report holds several controls of that type. ok
+// is false if it holds none.
+func (r *Report) SummaryOf(t NodeType) (s Summary, ok bool) {
+	for _, c := range r.Controls {
+		if c.Type == t {
+			s.Add(c.Summary)
+			ok = true
+		}
+	}
+	return s, ok
+}
+
+// JSON encodes the report, encoding each of its controls as their JSON
+// method does.
+func (r *Report) JSON() ([]byte, error) {
+	report := struct {
+		Controls []json.RawMessage `json:"controls"`
+		Summary  Summary           `json:"summary"`
+	}{Controls: []json.RawMessage{}, Summary: r.Summary}
+
+	for _, c := range r.Controls {
+		b, err := c.JSON()
+		if err != nil {
+			return nil, err
+		}
+		report.Controls = append(report.Controls, b)
+	}
+	return json.Marshal(report)
+}
@@ -0,0 +1,59 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewReport(t *testing.T) {
+	master := &Controls{ID: "1", Type: MASTER, Summary: Summary{Pass: 3, Fail: 1}}
+	node := &Controls{ID: "2", Type: NODE, Summary: Summary{Pass: 2, Warn: 2}}
+	etcd := &Controls{ID: "3", Type: ETCD, Summary: Summary{Fail: 1, Error: 1}}
+
+	r := NewReport(master, node, etcd)
+	if exp := (Summary{Pass: 5, Fail: 2, Warn: 2, Error: 1}); r.Summary != exp {
+		t.Errorf("expected %v, got %v", exp, r.Summary)
+	}
+	if s, ok := r.SummaryOf(NODE); !ok || s != node.Summary {
+		t.Errorf("expected the node summary %v, got %v %v", node.Summary, s, ok)
+	}
+	if _, ok := r.SummaryOf(FEDERATED); ok {
+		t.Errorf("expected no federated summary")
+	}
+
+	b, err := r.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out struct {
+		Controls []struct {
+			ID   string   `json:"id"`
+			Type NodeType `json:"node_type"`
+		} `json:"controls"`
+		Summary Summary `json:"summary"`
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("unexpected error: %v in %s", err, b)
+	}
+	if len(out.Controls) != 3 || out.Controls[1].ID != "2" || out.Controls[2].Type != ETCD || out.Summary != r.Summary {
+		t.Errorf("unexpected JSON %s", b)
+	}
+
+	if b, err := NewReport().JSON(); err != nil || string(b) != `{"controls":[],"summary":{"total_pass":0,"total_fail":0,"total_warn":0,"total_info":0,"total_skip":0,"total_na":0,"total_error":0,"total_critical_fail":0}}` {
+		t.Errorf("unexpected empty report %s, %v", b, err)
+	}
+}
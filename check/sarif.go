@@ -0,0 +1,130 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://schemastore.azurewebsites.net/schemas/json/sarif-2.1.0.json"
+	sarifToolURI = "https://github.com/aquasecurity/kube-bench"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	Help             sarifMessage `json:"help"`
+}
+
+type sarifResult struct {
+	RuleID  string       `json:"ruleId"`
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+// sarifFinding reports whether checks in state s are reported as SARIF
+// results, which hold findings only.
+func sarifFinding(s State) bool {
+	switch s {
+	case FAIL, WARN, INFO, ERROR:
+		return true
+	}
+	return false
+}
+
+// sarifLevel maps a check state to a SARIF result level.
+func sarifLevel(s State) string {
+	switch s {
+	case FAIL, ERROR:
+		return "error"
+	case WARN:
+		return "warning"
+	case INFO:
+		return "note"
+	}
+	return "none"
+}
+
+// SARIF encodes the results of last run to SARIF 2.1.0. Every FAIL, WARN,
+// INFO and ERROR check is reported as a result whose rule ID is the check
+// ID; checks that passed, were skipped or do not apply are left out.
+func (controls *Controls) SARIF() ([]byte, error) {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           "kube-bench",
+				Version:        controls.Version,
+				InformationURI: sarifToolURI,
+				Rules:          []sarifRule{},
+			},
+		},
+		Results: []sarifResult{},
+	}
+
+	for _, group := range flattenGroups(controls.Groups) {
+		for _, check := range group.Checks {
+			if !sarifFinding(check.State) {
+				continue
+			}
+
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               check.ID,
+				ShortDescription: sarifMessage{Text: check.Text},
+				Help:             sarifMessage{Text: check.RemediationFor(controls.Distribution)},
+			})
+
+			result := sarifResult{
+				RuleID:  check.ID,
+				Level:   sarifLevel(check.State),
+				Message: sarifMessage{Text: check.Text},
+			}
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	return json.Marshal(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestControls_SARIF(t *testing.T) {
+	c := &Controls{
+		Version: "1.13",
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", Text: "failing check", State: FAIL, Remediation: "fix it"},
+					{ID: "1.1.2", Text: "warning check", State: WARN},
+					{ID: "1.1.3", Text: "info check", State: INFO},
+					{ID: "1.1.4", Text: "skipped check", State: SKIP},
+					{ID: "1.1.5", Text: "passing check", State: PASS},
+					{ID: "1.1.6", Text: "not applicable check", State: NA},
+					{ID: "1.1.7", Text: "erroring check", State: ERROR},
+				},
+			},
+		},
+	}
+
+	out, err := c.SARIF()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(out, &log); err != nil {
+		t.Fatalf("failed to unmarshal SARIF output: %v", err)
+	}
+
+	if log.Version != sarifVersion || len(log.Runs) != 1 {
+		t.Fatalf("unexpected SARIF log: %+v", log)
+	}
+
+	run := log.Runs[0]
+	if run.Tool.Driver.Version != "1.13" {
+		t.Errorf("expected driver version 1.13, got %q", run.Tool.Driver.Version)
+	}
+
+	expected := []struct {
+		id    string
+		level string
+	}{
+		{"1.1.1", "error"},
+		{"1.1.2", "warning"},
+		{"1.1.3", "note"},
+		{"1.1.7", "error"},
+	}
+
+	if len(run.Results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(run.Results))
+	}
+
+	for i, e := range expected {
+		if run.Results[i].RuleID != e.id || run.Results[i].Level != e.level {
+			t.Errorf("result %d: expected %s/%s, got %s/%s", i, e.id, e.level, run.Results[i].RuleID, run.Results[i].Level)
+		}
+	}
+
+	for _, rule := range run.Tool.Driver.Rules {
+		if rule.ID == "1.1.5" || rule.ID == "1.1.6" {
+			t.Errorf("expected no rule for check %s, which is not a finding", rule.ID)
+		}
+	}
+
+	if run.Tool.Driver.Rules[0].Help.Text != "fix it" {
+		t.Errorf("expected remediation in rule help, got %q", run.Tool.Driver.Rules[0].Help.Text)
+	}
+}
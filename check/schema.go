@@ -0,0 +1,122 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+type schema map[string]interface{}
+
+// ControlsJSONSchema returns a JSON Schema describing controls files, for
+// editors to validate them as they are written. The schema is generated
+// from the yaml tags of Controls, Group and Check, so it accepts the same
+// keys as NewControls.
+func ControlsJSONSchema() ([]byte, error) {
+	s := schemaFor(reflect.TypeOf(Controls{}))
+	s["$schema"] = "http://json-schema.org/draft-07/schema#"
+	s["title"] = "kube-bench controls file"
+
+	// The leading "controls:" key of the files carries no value.
+	s["properties"].(schema)["controls"] = schema{}
+
+	// Subgroups refer to the definition of groups, as groups nest.
+	s["definitions"] = schema{"group": schemaFor(groupType)}
+
+	return json.MarshalIndent(s, "", "  ")
+}
+
+var (
+	nodeTypeType = reflect.TypeOf(NodeType(""))
+	stateType    = reflect.TypeOf(State(""))
+	binOpType    = reflect.TypeOf(binOp(""))
+	checkType    = reflect.TypeOf(Check{})
+	groupType    = reflect.TypeOf(Group{})
+)
+
+func schemaFor(t reflect.Type) schema {
+	switch t {
+	case nodeTypeType:
+		return schema{"type": "string", "enum": NodeTypes()}
+	case stateType:
+		return schema{"type": "string", "enum": []State{PASS, FAIL, WARN, INFO, SKIP, NA, ERROR}}
+	case binOpType:
+		return schema{"type": "string", "enum": []binOp{and, or}}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaFor(t.Elem())
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return schema{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+	case reflect.Struct:
+		props := schema{}
+		addProperties(props, t)
+		return schema{"type": "object", "properties": props, "additionalProperties": false}
+	}
+	return schema{}
+}
+
+// addProperties adds the schema of each field of struct type t to props,
+// keyed the way yaml.v2 names the field.
+func addProperties(props schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		tag := strings.Split(f.Tag.Get("yaml"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		if len(tag) > 1 && tag[1] == "inline" {
+			addProperties(props, f.Type)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		if t == groupType && f.Name == "SubGroups" {
+			props[name] = schema{"type": "array", "items": schema{"$ref": "#/definitions/group"}}
+			continue
+		}
+		if t == checkType && f.Name == "CheckCISLevel" {
+			// Levels are whole numbers, written with or without quotes.
+			props[name] = schema{"type": []string{"integer", "string"}, "minimum": 0, "pattern": "^[0-9]+$"}
+			continue
+		}
+		if t == checkType && f.Name == "Severity" {
+			props[name] = schema{"type": "string", "enum": Severities()}
+			continue
+		}
+		props[name] = schemaFor(f.Type)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestControlsJSONSchema(t *testing.T) {
+	out, err := ControlsJSONSchema()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var s map[string]interface{}
+	if err := json.Unmarshal(out, &s); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	props := s["properties"].(map[string]interface{})
+	typ := props["type"].(map[string]interface{})
+	if enum := typ["enum"].([]interface{}); len(enum) != len(NodeTypes()) || enum[0] != string(MASTER) {
+		t.Errorf("unexpected node types: %v", enum)
+	}
+
+	// Every key used by the shipped controls files must be in the schema.
+	files, err := filepath.Glob(filepath.Join(cfgDir, "*", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if filepath.Base(file) == "config.yaml" {
+			continue
+		}
+		in, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc interface{}
+		if err := yaml.Unmarshal(in, &doc); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		checkSchemaKeys(t, file, "", s, doc)
+	}
+}
+
+// checkSchemaKeys reports the keys of doc that s does not allow.
+func checkSchemaKeys(t *testing.T, file, path string, s map[string]interface{}, doc interface{}) {
+	switch v := doc.(type) {
+	case map[interface{}]interface{}:
+		props, _ := s["properties"].(map[string]interface{})
+		for k, val := range v {
+			key := k.(string)
+			if props == nil {
+				if extra, ok := s["additionalProperties"].(map[string]interface{}); ok {
+					checkSchemaKeys(t, file, path+"."+key, extra, val)
+				}
+				continue
+			}
+			p, ok := props[key].(map[string]interface{})
+			if !ok {
+				t.Errorf("%s: key %s%s is not in the schema", file, path, "."+key)
+				continue
+			}
+			checkSchemaKeys(t, file, path+"."+key, p, val)
+		}
+	case []interface{}:
+		items, _ := s["items"].(map[string]interface{})
+		for _, val := range v {
+			checkSchemaKeys(t, file, path+"[]", items, val)
+		}
+	}
+}
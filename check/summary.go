@@ -0,0 +1,284 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	// ExitPass is returned by ExitCode when no checks failed or warned.
+	ExitPass = 0
+	// ExitFail is returned by ExitCode when at least one check failed.
+	ExitFail = 1
+	// ExitWarn is returned by ExitCode when checks warned but none failed.
+	ExitWarn = 2
+	// ExitError is returned by ExitCode when the audit of some checks could
+	// not be run but none failed.
+	ExitError = 3
+)
+
+// ExitCode maps the summary to a process exit code: ExitFail (1) if any
+// check failed, ExitError (3) if none failed but some could not be
+// evaluated, ExitWarn (2) if checks only warned, and ExitPass (0)
+// otherwise.
+func (s Summary) ExitCode() int {
+	if s.Fail > 0 {
+		return ExitFail
+	}
+	if s.Error > 0 {
+		return ExitError
+	}
+	if s.Warn > 0 {
+		return ExitWarn
+	}
+	return ExitPass
+}
+
+// HasFailures reports whether any check failed.
+func (s Summary) HasFailures() bool {
+	return s.Fail > 0
+}
+
+// HasErrors reports whether the audit of any check could not be run.
+func (s Summary) HasErrors() bool {
+	return s.Error > 0
+}
+
+// HasFindings reports whether any check failed or warned.
+func (s Summary) HasFindings() bool {
+	return s.Fail > 0 || s.Warn > 0
+}
+
+// CriticalExitCode is like ExitCode, but only takes critical checks into
+// account: it returns ExitFail if any critical check failed, and ExitPass
+// otherwise.
+func (s Summary) CriticalExitCode() int {
+	if s.CriticalFail > 0 {
+		return ExitFail
+	}
+	return ExitPass
+}
+
+// String returns the summary on one line, such as
+// "PASS=120 FAIL=3 WARN=8 INFO=0 SKIP=45 NA=0 ERROR=0".
+func (s Summary) String() string {
+	return fmt.Sprintf("PASS=%d FAIL=%d WARN=%d INFO=%d SKIP=%d NA=%d ERROR=%d", s.Pass, s.Fail, s.Warn, s.Info, s.Skip, s.NA, s.Error)
+}
+
+// SummaryLine returns the summary of the last run on one line, prefixed
+// with the node type and version of the controls, such as
+// "master 1.13 PASS=120 FAIL=3 WARN=8 INFO=0 SKIP=45 NA=0 ERROR=0".
+func (controls *Controls) SummaryLine() string {
+	return fmt.Sprintf("%s %s %s", controls.Type, controls.Version, controls.Summary)
+}
+
+// Score returns the percentage of scored checks that passed, that is PASS
+// among PASS and FAIL, ignoring WARN, INFO, SKIP, NA and ERROR. It returns 0 if no
+// check passed or failed.
+func (s Summary) Score() float64 {
+	if s.Pass+s.Fail == 0 {
+		return 0
+	}
+	return 100 * float64(s.Pass) / float64(s.Pass+s.Fail)
+}
+
+// PartialScore is like Score, but gives partial credit to checks with
+// several test items: it returns the percentage of the test items of the
+// checks of the last run that passed, among the checks that passed or
+// failed. A check failing 3 of its 4 tests counts as 3 passes and 1 failure
+// rather than 1 failure. It returns 0 if no tests were run.
+func (controls *Controls) PartialScore() float64 {
+	var passed, total int
+	controls.EachCheck(func(group *Group, check *Check) {
+		if check.State == PASS || check.State == FAIL {
+			passed += check.PassedTests
+			total += check.TotalTests
+		}
+	})
+
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(passed) / float64(total)
+}
+
+// WeightedScore is like Score, but weights the checks of each CIS level of
+// the last run by weights[level], for example to make level 2 checks count
+// twice as much as level 1 checks. Levels without a weight count as 1.
+func (controls *Controls) WeightedScore(weights map[string]float64) float64 {
+	var pass, total float64
+	for level, s := range controls.SummaryLevelWise {
+		w, ok := weights[level]
+		if !ok {
+			w = 1
+		}
+		pass += w * float64(s.Pass)
+		total += w * float64(s.Pass+s.Fail)
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return 100 * pass / total
+}
+
+// Add accumulates the counts from other into s.
+func (s *Summary) Add(other Summary) {
+	s.Pass += other.Pass
+	s.Fail += other.Fail
+	s.Warn += other.Warn
+	s.Info += other.Info
+	s.Skip += other.Skip
+	s.NA += other.NA
+	s.Error += other.Error
+	s.CriticalFail += other.CriticalFail
+}
+
+// Over returns by how much each count of s exceeds the same count of
+// budget, or 0 for the counts within budget. For example, with a budget of
+// 5 warnings, a summary of 7 warnings is over by Summary{Warn: 2}. Counts
+// left 0 in budget allow none, so that every count is compared; callers
+// budgeting only some states can ignore the other fields of the result. The
+// ExitCode of the result gates a run on its failure and warning budgets.
+func (s Summary) Over(budget Summary) Summary {
+	over := func(n, max int) int {
+		if n > max {
+			return n - max
+		}
+		return 0
+	}
+	return Summary{
+		Pass:         over(s.Pass, budget.Pass),
+		Fail:         over(s.Fail, budget.Fail),
+		Warn:         over(s.Warn, budget.Warn),
+		Info:         over(s.Info, budget.Info),
+		Skip:         over(s.Skip, budget.Skip),
+		NA:           over(s.NA, budget.NA),
+		Error:        over(s.Error, budget.Error),
+		CriticalFail: over(s.CriticalFail, budget.CriticalFail),
+	}
+}
+
+// addCheck counts check in s according to its state.
+func (s *Summary) addCheck(check *Check) {
+	if n := s.counter(check.State); n != nil {
+		*n++
+	}
+	if check.State == FAIL && check.Critical {
+		s.CriticalFail++
+	}
+}
+
+// counter returns the field of s counting the checks in state, or nil if
+// checks in state are not counted.
+func (s *Summary) counter(state State) *int {
+	return stateCounter(state, &s.Pass, &s.Fail, &s.Warn, &s.Info, &s.Skip, &s.NA, &s.Error)
+}
+
+// stateCounter returns the one of the counters given that counts the checks
+// in state, or nil if checks in state are not counted, so that Summary and
+// Group count checks the same way.
+func stateCounter(state State, pass, fail, warn, info, skip, na, errs *int) *int {
+	switch state {
+	case PASS:
+		return pass
+	case FAIL:
+		return fail
+	case WARN:
+		return warn
+	case INFO:
+		return info
+	case SKIP:
+		return skip
+	case NA:
+		return na
+	case ERROR:
+		return errs
+	}
+	return nil
+}
+
+// SummaryAccumulator builds a Summary from checks. It is safe for
+// concurrent use, so that checks run in parallel, possibly from several
+// controls, can be summarized together. The zero value is an empty summary
+// ready to use.
+type SummaryAccumulator struct {
+	mu sync.Mutex
+	s  Summary
+}
+
+// Add counts check according to its state.
+func (a *SummaryAccumulator) Add(check *Check) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.s.addCheck(check)
+}
+
+// Result returns the summary of the checks added so far.
+func (a *SummaryAccumulator) Result() Summary {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.s
+}
+
+// MergeControls sums the summaries of the last run of each of cs, for
+// example to total the results for master and node checks.
+func MergeControls(cs ...*Controls) Summary {
+	var s Summary
+	for _, c := range cs {
+		s.Add(c.Summary)
+	}
+	return s
+}
+
+// MergeLevelWise sums the level-wise summaries of the last run of each of
+// cs, keyed by CIS level.
+func MergeLevelWise(cs ...*Controls) map[string]*Summary {
+	m := map[string]*Summary{}
+	for _, c := range cs {
+		for level, s := range c.SummaryLevelWise {
+			if _, ok := m[level]; !ok {
+				m[level] = &Summary{}
+			}
+			m[level].Add(*s)
+		}
+	}
+	return m
+}
+
+// LevelSummary is the summary of the checks of one CIS level.
+type LevelSummary struct {
+	Level   string  `json:"level"`
+	Summary Summary `json:"summary"`
+}
+
+// LevelSummaries returns the level-wise summaries of the last run ordered
+// by level, as "1" before "2" before "10". It is preferred to ranging over
+// SummaryLevelWise, whose order varies, and is empty before any run.
+func (controls *Controls) LevelSummaries() []LevelSummary {
+	ls := []LevelSummary{}
+	for level, s := range controls.SummaryLevelWise {
+		if s != nil {
+			ls = append(ls, LevelSummary{Level: level, Summary: *s})
+		}
+	}
+	sort.Slice(ls, func(i, j int) bool {
+		return CompareCheckIDs(ls[i].Level, ls[j].Level) < 0
+	})
+	return ls
+}
@@ -0,0 +1,230 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSummary_ExitCode(t *testing.T) {
+	cases := []struct {
+		summary Summary
+		exp     int
+	}{
+		{summary: Summary{}, exp: ExitPass},
+		{summary: Summary{Pass: 3, Info: 1, Skip: 2}, exp: ExitPass},
+		{summary: Summary{Pass: 3, Warn: 1}, exp: ExitWarn},
+		{summary: Summary{Pass: 3, Fail: 1}, exp: ExitFail},
+		{summary: Summary{Fail: 1, Warn: 1}, exp: ExitFail},
+		{summary: Summary{Pass: 3, Warn: 1, Error: 1}, exp: ExitError},
+		{summary: Summary{Fail: 1, Error: 1}, exp: ExitFail},
+	}
+
+	for _, c := range cases {
+		if code := c.summary.ExitCode(); code != c.exp {
+			t.Errorf("%+v: expected exit code %d, got %d", c.summary, c.exp, code)
+		}
+	}
+}
+
+func TestMergeControls(t *testing.T) {
+	master := &Controls{
+		Summary: Summary{Pass: 3, Fail: 1, Skip: 2},
+		SummaryLevelWise: map[string]*Summary{
+			"1": {Pass: 3, Fail: 1},
+			"2": {Skip: 2},
+		},
+	}
+	node := &Controls{
+		Summary: Summary{Pass: 1, Warn: 2, Info: 1},
+		SummaryLevelWise: map[string]*Summary{
+			"1": {Pass: 1, Warn: 2, Info: 1},
+		},
+	}
+
+	if s := MergeControls(master, node); s != (Summary{Pass: 4, Fail: 1, Warn: 2, Info: 1, Skip: 2}) {
+		t.Errorf("unexpected merged summary: %+v", s)
+	}
+
+	levels := MergeLevelWise(master, node)
+	if len(levels) != 2 {
+		t.Fatalf("expected 2 levels, got %d", len(levels))
+	}
+	if *levels["1"] != (Summary{Pass: 4, Fail: 1, Warn: 2, Info: 1}) {
+		t.Errorf("unexpected level 1 summary: %+v", *levels["1"])
+	}
+	if *levels["2"] != (Summary{Skip: 2}) {
+		t.Errorf("unexpected level 2 summary: %+v", *levels["2"])
+	}
+
+	if *master.SummaryLevelWise["1"] != (Summary{Pass: 3, Fail: 1}) {
+		t.Errorf("merging modified the source summaries")
+	}
+}
+
+func TestSummary_CriticalExitCode(t *testing.T) {
+	if code := (Summary{Fail: 3, Warn: 2}).CriticalExitCode(); code != ExitPass {
+		t.Errorf("expected non-critical failures to give exit code %d, got %d", ExitPass, code)
+	}
+	if code := (Summary{Fail: 3, CriticalFail: 1}).CriticalExitCode(); code != ExitFail {
+		t.Errorf("expected critical failures to give exit code %d, got %d", ExitFail, code)
+	}
+}
+
+func TestSummary_Score(t *testing.T) {
+	cases := []struct {
+		s   Summary
+		exp float64
+	}{
+		{Summary{Pass: 3, Fail: 1, Warn: 5, Info: 2, Skip: 7}, 75},
+		{Summary{Warn: 2, Info: 1}, 0},
+		{Summary{}, 0},
+	}
+
+	for _, c := range cases {
+		if score := c.s.Score(); score != c.exp {
+			t.Errorf("%+v: expected score %v, got %v", c.s, c.exp, score)
+		}
+	}
+}
+
+func TestControls_WeightedScore(t *testing.T) {
+	c := &Controls{
+		SummaryLevelWise: map[string]*Summary{
+			"1": {Pass: 1, Fail: 1},
+			"2": {Pass: 1},
+		},
+	}
+
+	if score := c.WeightedScore(nil); score != 200.0/3 {
+		t.Errorf("expected unweighted score %v, got %v", 200.0/3, score)
+	}
+	if score := c.WeightedScore(map[string]float64{"2": 2}); score != 75 {
+		t.Errorf("expected weighted score 75, got %v", score)
+	}
+	if score := (&Controls{}).WeightedScore(nil); score != 0 {
+		t.Errorf("expected score 0 without results, got %v", score)
+	}
+}
+
+func TestSummaryAccumulator(t *testing.T) {
+	var a SummaryAccumulator
+	var wg sync.WaitGroup
+
+	for _, state := range []State{PASS, FAIL, WARN, INFO, SKIP} {
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(state State) {
+				defer wg.Done()
+				a.Add(&Check{State: state, Critical: state == FAIL})
+			}(state)
+		}
+	}
+	wg.Wait()
+
+	exp := Summary{Pass: 10, Fail: 10, Warn: 10, Info: 10, Skip: 10, CriticalFail: 10}
+	if s := a.Result(); s != exp {
+		t.Errorf("expected %+v, got %+v", exp, s)
+	}
+}
+
+func TestSummary_String(t *testing.T) {
+	s := Summary{Pass: 120, Fail: 3, Warn: 8, Skip: 45}
+	if exp := "PASS=120 FAIL=3 WARN=8 INFO=0 SKIP=45 NA=0 ERROR=0"; s.String() != exp {
+		t.Errorf("expected %q, got %q", exp, s.String())
+	}
+
+	c := &Controls{Type: MASTER, Version: "1.13", Summary: s}
+	if exp := "master 1.13 PASS=120 FAIL=3 WARN=8 INFO=0 SKIP=45 NA=0 ERROR=0"; c.SummaryLine() != exp {
+		t.Errorf("expected %q, got %q", exp, c.SummaryLine())
+	}
+}
+
+func TestSummary_HasFailures(t *testing.T) {
+	cases := []struct {
+		s           Summary
+		hasFailures bool
+		hasFindings bool
+		hasErrors   bool
+	}{
+		{Summary{Pass: 3, Info: 1, Skip: 2}, false, false, false},
+		{Summary{Pass: 3, Warn: 1}, false, true, false},
+		{Summary{Fail: 1}, true, true, false},
+		{Summary{Pass: 3, Error: 2}, false, false, true},
+	}
+
+	for _, c := range cases {
+		if c.s.HasFailures() != c.hasFailures || c.s.HasFindings() != c.hasFindings || c.s.HasErrors() != c.hasErrors {
+			t.Errorf("%+v: expected HasFailures %v, HasFindings %v and HasErrors %v", c.s, c.hasFailures, c.hasFindings, c.hasErrors)
+		}
+	}
+}
+
+func TestControls_LevelSummaries(t *testing.T) {
+	c := &Controls{}
+	if ls := c.LevelSummaries(); ls == nil || len(ls) != 0 {
+		t.Errorf("expected no summaries before a run, got %+v", ls)
+	}
+
+	c.SummaryLevelWise = map[string]*Summary{
+		"10": {Pass: 3},
+		"2":  {Fail: 2},
+		"1":  {Pass: 1},
+	}
+	ls := c.LevelSummaries()
+	if len(ls) != 3 || ls[0].Level != "1" || ls[1].Level != "2" || ls[2].Level != "10" {
+		t.Fatalf("expected the levels in order, got %+v", ls)
+	}
+	if ls[1].Summary != (Summary{Fail: 2}) {
+		t.Errorf("expected the summary of level 2, got %+v", ls[1].Summary)
+	}
+}
+
+func TestSummary_Over(t *testing.T) {
+	budget := Summary{Pass: 100, Fail: 0, Warn: 5, Info: 10, Skip: 50, NA: 50}
+	cases := []struct {
+		summary Summary
+		exp     Summary
+	}{
+		{Summary{Pass: 120, Warn: 7}, Summary{Pass: 20, Warn: 2}},
+		{Summary{Fail: 3, Warn: 5, CriticalFail: 1}, Summary{Fail: 3, CriticalFail: 1}},
+		{Summary{Warn: 2, Info: 4, Skip: 10}, Summary{}},
+		{Summary{}, Summary{}},
+	}
+
+	for _, c := range cases {
+		if got := c.summary.Over(budget); got != c.exp {
+			t.Errorf("%v over %v: expected %v, got %v", c.summary, budget, c.exp, got)
+		}
+	}
+}
+
+func TestControls_PartialScore(t *testing.T) {
+	c := &Controls{Groups: []*Group{{Checks: []*Check{
+		{ID: "1", State: PASS, PassedTests: 2, TotalTests: 2},
+		{ID: "2", State: FAIL, PassedTests: 3, TotalTests: 4},
+		{ID: "3", State: FAIL, PassedTests: 0, TotalTests: 2},
+		{ID: "4", State: WARN},
+		{ID: "5", State: INFO, PassedTests: 1, TotalTests: 1},
+	}}}}
+
+	if score := c.PartialScore(); score != 62.5 {
+		t.Errorf("expected a partial score of 62.5, got %v", score)
+	}
+	if score := (&Controls{}).PartialScore(); score != 0 {
+		t.Errorf("expected 0 without tests, got %v", score)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// ansiColors holds the ANSI escape codes used to color each state, matching
+// the colors of the command line output.
+var ansiColors = map[State]string{
+	PASS:  "\x1b[32m",
+	FAIL:  "\x1b[31m",
+	WARN:  "\x1b[33m",
+	INFO:  "\x1b[34m",
+	SKIP:  "\x1b[35m",
+	NA:    "\x1b[36m",
+	ERROR: "\x1b[91m",
+}
+
+const ansiReset = "\x1b[0m"
+
+// WriteTable writes the results of last run to w as a table of check ID,
+// state and description under a header for each group, followed by the
+// summary. States are colored unless controls.NoColor is set, and PASS and
+// SKIP checks are left out if HidePassed and HideSkipped are set.
+func (controls *Controls) WriteTable(w io.Writer) error {
+	return controls.writeTable(w, flattenGroups(controls.Groups))
+}
+
+// writeTable writes the table of WriteTable for groups.
+func (controls *Controls) writeTable(w io.Writer, groups []*Group) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "%s %s\n", controls.ID, controls.Text)
+	for _, group := range groups {
+		// Lines without cells end the columns, so each group is aligned
+		// on its own.
+		fmt.Fprintf(tw, "\n== %s %s ==\n", group.ID, group.Text)
+		for _, check := range group.Checks {
+			if controls.hides(check) {
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", check.ID, controls.colorState(check.State), check.Text)
+		}
+	}
+
+	fmt.Fprintf(tw, "\n== Summary ==\n%s\n", controls.Summary)
+	return tw.Flush()
+}
+
+// hides reports whether check is left out of the tables of WriteTable,
+// Markdown and HTML, see HidePassed and HideSkipped.
+func (controls *Controls) hides(check *Check) bool {
+	return (controls.HidePassed && check.State == PASS) || (controls.HideSkipped && check.State == SKIP)
+}
+
+// colorState returns state in brackets, colored unless controls.NoColor is
+// set. Every state is colored so that colored cells have the same width.
+func (controls *Controls) colorState(state State) string {
+	s := fmt.Sprintf("[%s]", state)
+	if controls.NoColor {
+		return s
+	}
+	return ansiColors[state] + s + ansiReset
+}
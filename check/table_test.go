@@ -0,0 +1,133 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestControls_WriteTable(t *testing.T) {
+	c := &Controls{
+		ID:   "1",
+		Text: "Master Node Security Configuration",
+		Groups: []*Group{
+			{
+				ID:   "1.1",
+				Text: "API Server",
+				Checks: []*Check{
+					{ID: "1.1.1", Text: "first check", State: PASS},
+					{ID: "1.1.10", Text: "second check", State: FAIL},
+				},
+			},
+		},
+		Summary: Summary{Pass: 1, Fail: 1},
+		NoColor: true,
+	}
+
+	var b bytes.Buffer
+	if err := c.WriteTable(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := `1 Master Node Security Configuration
+
+== 1.1 API Server ==
+1.1.1   [PASS]  first check
+1.1.10  [FAIL]  second check
+
+== Summary ==
+PASS=1 FAIL=1 WARN=0 INFO=0 SKIP=0 NA=0 ERROR=0
+`
+	if b.String() != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, b.String())
+	}
+
+	c.NoColor = false
+	b.Reset()
+	if err := c.WriteTable(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(b.String(), "1.1.10  \x1b[31m[FAIL]\x1b[0m  second check") {
+		t.Errorf("expected colored states, got:\n%q", b.String())
+	}
+}
+
+func TestControls_WriteTableHidePassed(t *testing.T) {
+	c := &Controls{
+		ID:   "1",
+		Text: "Master Node Security Configuration",
+		Groups: []*Group{
+			{
+				ID:   "1.1",
+				Text: "API Server",
+				Checks: []*Check{
+					{ID: "1.1.1", Text: "passing check", State: PASS},
+					{ID: "1.1.2", Text: "failing check", State: FAIL},
+					{ID: "1.1.3", Text: "skipped check", State: SKIP},
+				},
+			},
+		},
+		Summary:    Summary{Pass: 1, Fail: 1, Skip: 1},
+		NoColor:    true,
+		HidePassed: true,
+	}
+
+	var b bytes.Buffer
+	if err := c.WriteTable(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := `1 Master Node Security Configuration
+
+== 1.1 API Server ==
+1.1.2  [FAIL]  failing check
+1.1.3  [SKIP]  skipped check
+
+== Summary ==
+PASS=1 FAIL=1 WARN=0 INFO=0 SKIP=1 NA=0 ERROR=0
+`
+	if b.String() != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, b.String())
+	}
+
+	c.HideSkipped = true
+	b.Reset()
+	if err := c.WriteTable(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(b.String(), "1.1.3") || !strings.Contains(b.String(), "1.1.2") {
+		t.Errorf("expected only the failing check to be listed, got:\n%s", b.String())
+	}
+
+	md, err := c.Markdown()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	html, err := c.HTML()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, out := range map[string][]byte{"markdown": md, "HTML": html} {
+		s := string(out)
+		if strings.Contains(s, "passing check") || strings.Contains(s, "skipped check") || !strings.Contains(s, "failing check") {
+			t.Errorf("%s: expected only the failing check to be listed, got:\n%s", name, s)
+		}
+	}
+	if len(c.Groups[0].Checks) != 3 || c.Summary.Pass != 1 {
+		t.Errorf("expected the controls to be left unchanged, got %+v", c)
+	}
+}
@@ -26,7 +26,7 @@ import (
 // flag: OPTION
 // set: (true|false)
 // compare:
-//   op: (eq|gt|gte|lt|lte|has)
+//   op: (eq|noteq|gt|gte|lt|lte|has|nothave|regex)
 //   value: val
 
 type binOp string
@@ -50,12 +50,28 @@ type compare struct {
 }
 
 type testOutput struct {
-	testResult   bool
-	actualResult string
+	testResult     bool
+	actualResult   string
+	expectedResult string
+	// passed counts the test items that passed out of total.
+	passed int
+	total  int
+}
+
+// expected describes the result the test item passes for, such as
+// "--anonymous-auth eq false".
+func (t *testItem) expected() string {
+	switch {
+	case !t.Set:
+		return t.Flag + " is not set"
+	case t.Compare.Op == "":
+		return t.Flag + " is set"
+	}
+	return fmt.Sprintf("%s %s %s", t.Flag, t.Compare.Op, t.Compare.Value)
 }
 
 func (t *testItem) execute(s string) *testOutput {
-	result := &testOutput{}
+	result := &testOutput{expectedResult: t.expected()}
 	match := strings.Contains(s, t.Flag)
 
 	if t.Set {
@@ -124,6 +140,12 @@ func (t *testItem) execute(s string) *testOutput {
 
 			case "nothave":
 				result.testResult = !strings.Contains(flagVal, t.Compare.Value)
+
+			case "regex":
+				// Patterns are checked when the controls are loaded, so
+				// an invalid one only fails the test.
+				re, err := regexp.Compile(t.Compare.Value)
+				result.testResult = err == nil && re.MatchString(flagVal)
 			}
 		} else {
 			result.testResult = isset
@@ -141,6 +163,23 @@ type tests struct {
 	BinOp     binOp       `yaml:"bin_op"`
 }
 
+// clone returns a copy of ts that shares nothing with it.
+func (ts *tests) clone() *tests {
+	if ts == nil {
+		return nil
+	}
+
+	c := &tests{BinOp: ts.BinOp}
+	if ts.TestItems != nil {
+		c.TestItems = make([]*testItem, len(ts.TestItems))
+		for i, t := range ts.TestItems {
+			item := *t
+			c.TestItems[i] = &item
+		}
+	}
+	return c
+}
+
 func (ts *tests) execute(s string) *testOutput {
 	finalOutput := &testOutput{}
 
@@ -177,8 +216,24 @@ func (ts *tests) execute(s string) *testOutput {
 		}
 	}
 
+	expected := make([]string, len(res))
+	for i := range res {
+		expected[i] = res[i].expectedResult
+	}
+	op := ts.BinOp
+	if op == "" {
+		op = and
+	}
+
 	finalOutput.testResult = result
+	finalOutput.total = len(res)
+	for i := range res {
+		if res[i].testResult {
+			finalOutput.passed++
+		}
+	}
 	finalOutput.actualResult = res[0].actualResult
+	finalOutput.expectedResult = strings.Join(expected, " "+string(op)+" ")
 
 	return finalOutput
 }
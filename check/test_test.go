@@ -110,6 +110,10 @@ func TestTestExecute(t *testing.T) {
 			controls.Groups[0].Checks[13],
 			"2:45 ../kubernetes/kube-apiserver --option --admission-control=Something ---audit-log-maxage=40",
 		},
+		{
+			controls.Groups[0].Checks[14],
+			"2:45 ../kubernetes/kube-apiserver --tls-min-version=VersionTLS12 --option",
+		},
 	}
 
 	for _, c := range cases {
@@ -119,3 +123,29 @@ func TestTestExecute(t *testing.T) {
 		}
 	}
 }
+
+func TestTestExecuteExpected(t *testing.T) {
+	ts := controls.Groups[0].Checks[14].Tests
+
+	res := ts.execute("2:45 ../kubernetes/kube-apiserver --tls-min-version=VersionTLS10")
+	if res.testResult {
+		t.Errorf("expected TLS 1.0 not to match")
+	}
+	if exp := "--tls-min-version regex ^VersionTLS1[23]$"; res.expectedResult != exp {
+		t.Errorf("expected %q, got %q", exp, res.expectedResult)
+	}
+	if exp := "versiontls10"; res.actualResult != exp {
+		t.Errorf("expected %q, got %q", exp, res.actualResult)
+	}
+
+	ts = &tests{
+		TestItems: []*testItem{
+			{Flag: "--profiling", Set: false},
+			{Flag: "--anonymous-auth", Set: true},
+		},
+		BinOp: or,
+	}
+	if exp := "--profiling is not set or --anonymous-auth is set"; ts.execute("").expectedResult != exp {
+		t.Errorf("expected %q, got %q", exp, ts.execute("").expectedResult)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import "strings"
+
+// Translation holds the text and remediation of a check in other
+// languages, keyed by locale such as "fr" or "pt-BR".
+type Translation struct {
+	Text        map[string]string `yaml:"text" json:"text"`
+	Remediation map[string]string `yaml:"remediation" json:"remediation"`
+}
+
+// Translate replaces the text and remediation of every check loaded by
+// their translation into locale from table, which is keyed by check ID. A
+// locale such as "pt-BR" falls back to "pt" when it has no translation.
+// Checks without a translation keep the text of the controls file, even if
+// they were translated into another locale before, and an empty locale
+// restores the text of the controls file for every check.
+func (controls *Controls) Translate(locale string, table map[string]Translation) {
+	for _, group := range controls.everyGroup() {
+		for _, check := range group.Checks {
+			if check.untranslated == nil {
+				check.untranslated = &checkText{text: check.Text, remediation: check.Remediation}
+			}
+			check.Text = check.untranslated.text
+			check.Remediation = check.untranslated.remediation
+
+			if locale == "" {
+				continue
+			}
+			tr, ok := table[check.ID]
+			if !ok {
+				continue
+			}
+			if text, ok := lookupLocale(tr.Text, locale); ok {
+				check.Text = text
+			}
+			if remediation, ok := lookupLocale(tr.Remediation, locale); ok {
+				check.Remediation = remediation
+			}
+		}
+	}
+}
+
+// lookupLocale returns the entry of m for locale, or for its language if
+// m has no entry for locale.
+func lookupLocale(m map[string]string, locale string) (string, bool) {
+	if s, ok := m[locale]; ok {
+		return s, true
+	}
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		s, ok := m[locale[:i]]
+		return s, ok
+	}
+	return "", false
+}
@@ -0,0 +1,62 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import "testing"
+
+func TestControls_Translate(t *testing.T) {
+	c := &Controls{
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", Text: "Ensure that anonymous auth is disabled", Remediation: "Set --anonymous-auth=false"},
+					{ID: "1.1.2", Text: "Ensure that profiling is disabled", Remediation: "Set --profiling=false"},
+				},
+			},
+		},
+	}
+	table := map[string]Translation{
+		"1.1.1": {
+			Text:        map[string]string{"fr": "Vérifier que l'authentification anonyme est désactivée", "pt": "Garantir que a autenticação anônima está desativada"},
+			Remediation: map[string]string{"fr": "Définir --anonymous-auth=false"},
+		},
+		"1.1.2": {
+			Text: map[string]string{"fr": "Vérifier que le profilage est désactivé"},
+		},
+	}
+
+	c.Translate("fr", table)
+	first, second := c.Groups[0].Checks[0], c.Groups[0].Checks[1]
+	if first.Text != "Vérifier que l'authentification anonyme est désactivée" || first.Remediation != "Définir --anonymous-auth=false" {
+		t.Errorf("expected the French text, got %q %q", first.Text, first.Remediation)
+	}
+	if second.Remediation != "Set --profiling=false" {
+		t.Errorf("expected the untranslated remediation to be kept, got %q", second.Remediation)
+	}
+
+	c.Translate("pt-BR", table)
+	if first.Text != "Garantir que a autenticação anônima está desativada" || first.Remediation != "Set --anonymous-auth=false" {
+		t.Errorf("expected the Portuguese text, got %q %q", first.Text, first.Remediation)
+	}
+	if second.Text != "Ensure that profiling is disabled" {
+		t.Errorf("expected untranslated checks to fall back to the default text, got %q", second.Text)
+	}
+
+	c.Translate("", table)
+	if first.Text != "Ensure that anonymous auth is disabled" {
+		t.Errorf("expected the default text to be restored, got %q", first.Text)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CheckCompatibility returns an error if the controls declare, through
+// min_kube_bench_version or max_kube_bench_version, that they are not meant
+// to be run by the given version of kube-bench. Both bounds are inclusive
+// and are semantic versions such as "0.2.0"; controls without them are
+// compatible with every version.
+func (controls *Controls) CheckCompatibility(kubeBenchVersion string) error {
+	v, err := parseSemver(kubeBenchVersion)
+	if err != nil {
+		return fmt.Errorf("invalid kube-bench version: %v", err)
+	}
+
+	if controls.MinKubeBenchVersion != "" {
+		min, err := parseSemver(controls.MinKubeBenchVersion)
+		if err != nil {
+			return fmt.Errorf("invalid min_kube_bench_version: %v", err)
+		}
+		if v.compare(min) < 0 {
+			return fmt.Errorf("controls %s require kube-bench %s or later, not %s", controls.ID, controls.MinKubeBenchVersion, kubeBenchVersion)
+		}
+	}
+
+	if controls.MaxKubeBenchVersion != "" {
+		max, err := parseSemver(controls.MaxKubeBenchVersion)
+		if err != nil {
+			return fmt.Errorf("invalid max_kube_bench_version: %v", err)
+		}
+		if v.compare(max) > 0 {
+			return fmt.Errorf("controls %s require kube-bench %s or earlier, not %s", controls.ID, controls.MaxKubeBenchVersion, kubeBenchVersion)
+		}
+	}
+
+	return nil
+}
+
+// IsKubeVersion reports whether v is a version that KubeVersion accepts,
+// such as "1.13" or "v1.13.4", unlike the names of other benchmarks such as
+// "ocp-3.10".
+func IsKubeVersion(v string) bool {
+	_, err := parseSemver(v)
+	return err == nil
+}
+
+// checkKubeVersion returns an error if controls.KubeVersion is set but is
+// not a version.
+func (controls *Controls) checkKubeVersion() error {
+	if controls.KubeVersion == "" {
+		return nil
+	}
+	if _, err := parseSemver(controls.KubeVersion); err != nil {
+		return fmt.Errorf("invalid Kubernetes version: %v", err)
+	}
+	return nil
+}
+
+// versionSkipReason returns why check does not apply to controls.KubeVersion,
+// or "" if it applies. Both bounds are inclusive. The pre-release of the
+// Kubernetes version, such as "-gke.100", is ignored so that provider builds
+// are gated like the release they are based on.
+func (controls *Controls) versionSkipReason(check *Check) (string, error) {
+	if controls.KubeVersion == "" || (check.MinVersion == "" && check.MaxVersion == "") {
+		return "", nil
+	}
+
+	v, err := parseSemver(controls.KubeVersion)
+	if err != nil {
+		return "", fmt.Errorf("invalid Kubernetes version: %v", err)
+	}
+	v.prerelease = ""
+
+	if check.MinVersion != "" {
+		min, err := parseSemver(check.MinVersion)
+		if err != nil {
+			return "", fmt.Errorf("check %s: invalid min_version: %v", check.ID, err)
+		}
+		if v.compare(min) < 0 {
+			return fmt.Sprintf("requires Kubernetes %s or later, not %s", check.MinVersion, controls.KubeVersion), nil
+		}
+	}
+
+	if check.MaxVersion != "" {
+		max, err := parseSemver(check.MaxVersion)
+		if err != nil {
+			return "", fmt.Errorf("check %s: invalid max_version: %v", check.ID, err)
+		}
+		if v.compare(max) > 0 {
+			return fmt.Sprintf("requires Kubernetes %s or earlier, not %s", check.MaxVersion, controls.KubeVersion), nil
+		}
+	}
+
+	return "", nil
+}
+
+// semver is a parsed semantic version. Build metadata is dropped.
+type semver struct {
+	parts      [3]int
+	prerelease string
+}
+
+// parseSemver parses versions such as "1.2.3", "v1.2.3-rc.1" or "1.2",
+// where a missing minor or patch number is taken to be 0.
+func parseSemver(s string) (semver, error) {
+	var v semver
+
+	t := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.Index(t, "+"); i >= 0 {
+		t = t[:i]
+	}
+	if i := strings.Index(t, "-"); i >= 0 {
+		v.prerelease = t[i+1:]
+		t = t[:i]
+	}
+
+	nums := strings.Split(t, ".")
+	if len(nums) > 3 {
+		return v, fmt.Errorf("%q is not a semantic version", s)
+	}
+	for i, n := range nums {
+		x, err := strconv.Atoi(n)
+		if err != nil || x < 0 {
+			return v, fmt.Errorf("%q is not a semantic version", s)
+		}
+		v.parts[i] = x
+	}
+	return v, nil
+}
+
+// compare returns -1, 0 or 1 as v is before, equal to or after w. A
+// pre-release comes before the release it precedes.
+func (v semver) compare(w semver) int {
+	for i := range v.parts {
+		if v.parts[i] != w.parts[i] {
+			if v.parts[i] < w.parts[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case v.prerelease == w.prerelease:
+		return 0
+	case v.prerelease == "":
+		return 1
+	case w.prerelease == "":
+		return -1
+	case v.prerelease < w.prerelease:
+		return -1
+	}
+	return 1
+}
@@ -0,0 +1,109 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestControls_CheckCompatibility(t *testing.T) {
+	c, err := NewControls(MASTER, "1", []byte(`---
+controls:
+id: 1
+text: "Master Node Security Configuration"
+type: "master"
+min_kube_bench_version: "0.2.0"
+max_kube_bench_version: "0.3.1"
+groups: []
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		version string
+		expErr  bool
+	}{
+		{version: "0.2.0"},
+		{version: "v0.2.5"},
+		{version: "0.3.1"},
+		{version: "0.3"},
+		{version: "0.2.0-rc.1", expErr: true},
+		{version: "0.1.9", expErr: true},
+		{version: "0.3.2", expErr: true},
+		{version: "1.0.0", expErr: true},
+		{version: "latest", expErr: true},
+	}
+
+	for _, tc := range cases {
+		err := c.CheckCompatibility(tc.version)
+		if tc.expErr && err == nil {
+			t.Errorf("%s: expected an error", tc.version)
+		}
+		if !tc.expErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.version, err)
+		}
+	}
+
+	if err := (&Controls{}).CheckCompatibility("0.0.1"); err != nil {
+		t.Errorf("expected controls without bounds to be compatible, got %v", err)
+	}
+}
+
+func TestControls_RunGroupKubeVersion(t *testing.T) {
+	c := &Controls{
+		UserCISLevel: "1",
+		KubeVersion:  "1.24",
+		Groups: []*Group{
+			{
+				ID: "1.1",
+				Checks: []*Check{
+					{ID: "1.1.1", Type: "manual", CheckCISLevel: "1"},
+					{ID: "1.1.2", Type: "manual", CheckCISLevel: "1", MaxVersion: "1.23"},
+					{ID: "1.1.3", Type: "manual", CheckCISLevel: "1", MinVersion: "1.25"},
+					{ID: "1.1.4", Type: "manual", CheckCISLevel: "1", MinVersion: "1.20", MaxVersion: "1.24"},
+					{ID: "1.1.5", Type: "manual", CheckCISLevel: "1", MinVersion: "latest"},
+				},
+			},
+		},
+	}
+
+	summary, err := c.RunGroup()
+	if err == nil || !strings.Contains(err.Error(), "check 1.1.5: invalid min_version") {
+		t.Errorf("expected an error for the invalid version, got %v", err)
+	}
+	if summary.Warn != 2 || summary.Skip != 2 {
+		t.Errorf("expected 2 checks run and 2 skipped, got %+v", summary)
+	}
+
+	checks := c.Groups[0].Checks
+	if exp := "requires Kubernetes 1.23 or earlier, not 1.24"; checks[1].SkipReason != exp {
+		t.Errorf("expected reason %q, got %q", exp, checks[1].SkipReason)
+	}
+	if exp := "requires Kubernetes 1.25 or later, not 1.24"; checks[2].SkipReason != exp {
+		t.Errorf("expected reason %q, got %q", exp, checks[2].SkipReason)
+	}
+
+	c.KubeVersion = "v1.25.3-gke.100"
+	if summary, _ = c.RunChecks("1.1.2", "1.1.3"); summary.Warn != 1 || summary.Skip != 1 {
+		t.Errorf("expected the provider build to be gated as its release, got %+v", summary)
+	}
+
+	c.KubeVersion = "one"
+	if _, err := c.RunGroup(); err == nil || !strings.Contains(err.Error(), "invalid Kubernetes version") {
+		t.Errorf("expected an error for the invalid Kubernetes version, got %v", err)
+	}
+}
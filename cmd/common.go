@@ -19,6 +19,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/aquasecurity/kube-bench/check"
 	"github.com/golang/glog"
@@ -27,6 +28,11 @@ import (
 
 var (
 	errmsgs string
+
+	// clusterVersion is the Kubernetes version the controls were loaded
+	// for, either given with --version or detected, or "" if --version
+	// names another benchmark, such as ocp-3.10.
+	clusterVersion string
 )
 
 func runChecks(nodetype check.NodeType, level string) {
@@ -64,6 +70,13 @@ func runChecks(nodetype check.NodeType, level string) {
 	if err != nil {
 		exitWithError(fmt.Errorf("error setting up %s controls: %v", nodetype, err))
 	}
+	controls.DryRun = dryRun
+	controls.Shell = shell
+	controls.Distribution = distribution
+	controls.ManagedControlPlane = managedCP
+	controls.KubeVersion = clusterVersion
+	controls.Platform = platform
+	controls.HidePassed = hidePassed
 
 	if groupList != "" && checkList == "" {
 		ids := cleanIDs(groupList)
@@ -71,6 +84,9 @@ func runChecks(nodetype check.NodeType, level string) {
 		if err != nil{
 			continueWithError(err, "")
 		}
+		if len(controls.UnmatchedGroupIDs) > 0 {
+			continueWithError(nil, fmt.Sprintf("No %s groups matched: %s", nodetype, strings.Join(controls.UnmatchedGroupIDs, ", ")))
+		}
 	} else if checkList != "" && groupList == "" {
 		ids := cleanIDs(checkList)
 		summary, err = controls.RunChecks(ids...)
@@ -86,7 +102,7 @@ func runChecks(nodetype check.NodeType, level string) {
 		}
 	}
 	// if we successfully ran some tests and it's json format, ignore the warnings
-	if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0 || summary.Skip > 0) && jsonFmt {
+	if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0 || summary.Skip > 0 || summary.NA > 0 || summary.Error > 0) && jsonFmt {
 		out, err := controls.JSON()
 		if err != nil {
 			exitWithError(fmt.Errorf("failed to output in JSON format: %v", err))
@@ -95,7 +111,7 @@ func runChecks(nodetype check.NodeType, level string) {
 		fmt.Println(string(out))
 	} else {
 		// if we want to store in PostgreSQL, convert to JSON and save it
-		if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0 || summary.Skip > 0 ) && pgSQL {
+		if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0 || summary.Skip > 0 || summary.NA > 0 || summary.Error > 0) && pgSQL {
 			out, err := controls.JSON()
 			if err != nil {
 				exitWithError(fmt.Errorf("failed to output in JSON format: %v", err))
@@ -123,10 +139,7 @@ func prettyPrint(r *check.Controls, summary check.Summary) {
 	if !noResults {
 		colorPrint(check.INFO, fmt.Sprintf("%s %s\n", r.ID, r.Text))
 		for _, g := range r.Groups {
-			colorPrint(check.INFO, fmt.Sprintf("%s %s\n", g.ID, g.Text))
-			for _, c := range g.Checks {
-				colorPrint(c.State, fmt.Sprintf("%s %s\n", c.ID, c.Text))
-			}
+			printGroup(g)
 		}
 
 		fmt.Println()
@@ -136,23 +149,22 @@ func prettyPrint(r *check.Controls, summary check.Summary) {
 	if !noRemediations {
 		if summary.Fail > 0 || summary.Warn > 0 {
 			colors[check.WARN].Printf("== Remediations ==\n")
-			for _, g := range r.Groups {
-				for _, c := range g.Checks {
-					if c.State == check.FAIL || c.State == check.WARN {
-						fmt.Printf("%s %s\n", c.ID, c.Remediation)
-					}
+			r.EachCheck(func(g *check.Group, c *check.Check) {
+				if c.State == check.FAIL || c.State == check.WARN {
+					fmt.Printf("%s %s\n", c.ID, c.RemediationFor(distribution))
 				}
-			}
+			})
 			fmt.Println()
 		}
 	}
 
 	//Print summary Level-wise
 	if !noSummary {
-		for l, s := range r.SummaryLevelWise{
-			fmt.Printf("== Summary Level "+l+" ==\n")
-			fmt.Printf("%d checks PASS\n%d checks FAIL\n%d checks WARN\n%d checks INFO\n%d checks SKIP\n",
-				s.Pass, s.Fail, s.Warn, s.Info, s.Skip,
+		for _, ls := range r.LevelSummaries() {
+			s := ls.Summary
+			fmt.Printf("== Summary Level %s ==\n", ls.Level)
+			fmt.Printf("%d checks PASS\n%d checks FAIL\n%d checks WARN\n%d checks INFO\n%d checks SKIP\n%d checks NA\n%d checks ERROR\n",
+				s.Pass, s.Fail, s.Warn, s.Info, s.Skip, s.NA, s.Error,
 			)
 		}
 
@@ -164,6 +176,8 @@ func prettyPrint(r *check.Controls, summary check.Summary) {
 		var res check.State
 		if summary.Fail > 0 {
 			res = check.FAIL
+		} else if summary.Error > 0 {
+			res = check.ERROR
 		} else if summary.Warn > 0 {
 			res = check.WARN
 		} else {
@@ -171,12 +185,27 @@ func prettyPrint(r *check.Controls, summary check.Summary) {
 		}
 
 		colors[res].Printf("== Summary ==\n")
-		fmt.Printf("%d checks PASS\n%d checks FAIL\n%d checks WARN\n%d checks INFO\n%d checks SKIP\n",
-			summary.Pass, summary.Fail, summary.Warn, summary.Info, summary.Skip,
+		fmt.Printf("%d checks PASS\n%d checks FAIL\n%d checks WARN\n%d checks INFO\n%d checks SKIP\n%d checks NA\n%d checks ERROR\n",
+			summary.Pass, summary.Fail, summary.Warn, summary.Info, summary.Skip, summary.NA, summary.Error,
 		)
 	}
 }
 
+// printGroup outputs the results of the checks of a group, followed by those
+// of its subgroups. Passing checks are left out with --hide-passed.
+func printGroup(g *check.Group) {
+	colorPrint(check.INFO, fmt.Sprintf("%s %s\n", g.ID, g.Text))
+	for _, c := range g.Checks {
+		if hidePassed && c.State == check.PASS {
+			continue
+		}
+		colorPrint(c.State, fmt.Sprintf("%s %s\n", c.ID, c.Text))
+	}
+	for _, sub := range g.SubGroups {
+		printGroup(sub)
+	}
+}
+
 // loadConfig finds the correct config dir based on the kubernetes version,
 // merges any specific config.yaml file found with the main config
 // and returns the benchmark file to use.
@@ -200,6 +229,7 @@ func loadConfig(nodetype check.NodeType) string {
 			exitWithError(fmt.Errorf("Version check failed: %s\nAlternatively, you can specify the version with --version", err))
 		}
 	}
+	clusterVersion = checksKubeVersion(kubeVersion, runningVersion)
 	path, err := getConfigFilePath(kubeVersion, runningVersion, file)
 	if err != nil {
 		exitWithError(fmt.Errorf("can't find %s controls file in %s: %v", nodetype, cfgDir, err))
@@ -41,6 +41,12 @@ var (
 	noResults          bool
 	noSummary          bool
 	noRemediations     bool
+	dryRun             bool
+	shell              string
+	distribution       string
+	managedCP          bool
+	hidePassed         bool
+	platform           string
 	level              string
 )
 
@@ -80,20 +86,26 @@ func init() {
 	RootCmd.PersistentFlags().BoolVar(&noRemediations, "noremediations", false, "Disable printing of remediations section")
 	RootCmd.PersistentFlags().BoolVar(&jsonFmt, "json", false, "Prints the results as JSON")
 	RootCmd.PersistentFlags().BoolVar(&pgSQL, "pgsql", false, "Save the results to PostgreSQL")
+	RootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "List the checks that would run without running them")
+	RootCmd.PersistentFlags().StringVar(&distribution, "distribution", "", "Only show remediations for this Kubernetes distribution, such as kubeadm")
+	RootCmd.PersistentFlags().BoolVar(&managedCP, "managed-control-plane", false, "Mark master checks NA, for clusters whose master nodes cannot be inspected")
+	RootCmd.PersistentFlags().StringVar(&platform, "platform", "", "Skip the checks that do not apply to this platform, such as eks or openshift")
+	RootCmd.PersistentFlags().BoolVar(&hidePassed, "hide-passed", false, "Leave passing checks out of the results, while still counting them in the summary")
+	RootCmd.PersistentFlags().StringVar(&shell, "shell", check.DefaultShell, "Shell used to look up audit commands")
 
 	RootCmd.PersistentFlags().StringVarP(
 		&checkList,
 		"check",
 		"c",
 		"",
-		`A comma-delimited list of checks to run as specified in CIS document. Example --check="1.1.1,1.1.2"`,
+		`A comma-delimited list of checks, check ranges or check patterns to run as specified in CIS document. Example --check="1.1.1,1.1.2,1.2.1-1.2.5,1.3.*"`,
 	)
 	RootCmd.PersistentFlags().StringVarP(
 		&groupList,
 		"group",
 		"g",
 		"",
-		`Run all the checks under this comma-delimited list of groups. Example --group="1.1"`,
+		`Run all the checks under this comma-delimited list of groups, including the groups below them. Example --group="1.1"`,
 	)
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./cfg/config.yaml)")
 	RootCmd.PersistentFlags().StringVarP(&cfgDir, "config-dir", "D", "./cfg/", "config directory")
@@ -18,11 +18,13 @@ import (
 var (
 	// Print colors
 	colors = map[check.State]*color.Color{
-		check.PASS: color.New(color.FgGreen),
-		check.FAIL: color.New(color.FgRed),
-		check.WARN: color.New(color.FgYellow),
-		check.INFO: color.New(color.FgBlue),
-		check.SKIP: color.New(color.FgMagenta),
+		check.PASS:  color.New(color.FgGreen),
+		check.FAIL:  color.New(color.FgRed),
+		check.WARN:  color.New(color.FgYellow),
+		check.INFO:  color.New(color.FgBlue),
+		check.SKIP:  color.New(color.FgMagenta),
+		check.NA:    color.New(color.FgCyan),
+		check.ERROR: color.New(color.FgHiRed),
 	}
 )
 
@@ -143,6 +145,21 @@ func getConfigFilePath(specifiedVersion string, runningVersion string, filename
 	}
 }
 
+// checksKubeVersion returns the Kubernetes version to gate checks on: the
+// version given with --version, or else the running version. Versions
+// that are not Kubernetes versions, such as ocp-3.10 naming the config
+// directory of another benchmark, leave checks ungated.
+func checksKubeVersion(specifiedVersion, runningVersion string) string {
+	v := specifiedVersion
+	if v == "" {
+		v = runningVersion
+	}
+	if !check.IsKubeVersion(v) {
+		return ""
+	}
+	return v
+}
+
 // decrementVersion decrements the version number
 // We want to decrement individually even through versions where we don't supply test files
 // just in case someone wants to specify their own test files for that version
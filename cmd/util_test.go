@@ -22,6 +22,7 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/aquasecurity/kube-bench/check"
 	"github.com/spf13/viper"
 )
 
@@ -442,3 +443,44 @@ func TestGetConfigFilePath(t *testing.T) {
 		})
 	}
 }
+
+func TestChecksKubeVersion(t *testing.T) {
+	cases := []struct {
+		specifiedVersion string
+		runningVersion   string
+		exp              string
+	}{
+		{runningVersion: "1.13", exp: "1.13"},
+		{specifiedVersion: "1.11", exp: "1.11"},
+		{specifiedVersion: "ocp-3.10", exp: ""},
+		{exp: ""},
+	}
+
+	for _, c := range cases {
+		if v := checksKubeVersion(c.specifiedVersion, c.runningVersion); v != c.exp {
+			t.Errorf("%q %q: expected %q, got %q", c.specifiedVersion, c.runningVersion, c.exp, v)
+		}
+	}
+}
+
+func TestOCPVersionRunsChecks(t *testing.T) {
+	cfgDir = "../cfg"
+	path, err := getConfigFilePath("ocp-3.10", "", "master.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	in, err := ioutil.ReadFile(filepath.Join(path, "master.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	controls, err := check.NewControls(check.MASTER, "1", in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	controls.DryRun = true
+	controls.KubeVersion = checksKubeVersion("ocp-3.10", "")
+	if summary, err := controls.RunGroup(); err != nil || summary.Skip == 0 {
+		t.Errorf("expected the ocp-3.10 checks to be listed, got %v, %v", summary, err)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package server serves the results of running controls over HTTP, so that
+// a collector can pull them from kube-bench running on each node rather than
+// scraping its logs.
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// DefaultLevel is the CIS level run when a request does not give one.
+const DefaultLevel = check.DefaultLevel
+
+// LoadFunc returns the controls to run for a node type and CIS level, for
+// example by reading the controls file of the node type with NewControls.
+type LoadFunc func(t check.NodeType, level string) (*check.Controls, error)
+
+// Server is an http.Handler that runs controls and responds with their
+// results in the format of Controls.JSON.
+//
+// A request selects the controls with the "node" and "level" query
+// parameters, and may restrict the run to some groups or checks with a
+// comma-separated list of IDs in the "group" or "check" parameter, as the
+// --group and --check flags do. For example:
+//
+//	GET /?node=node&level=1&check=4.1.1,4.2.*
+//
+// The level must be a non-negative integer. The controls are loaded once for
+// each node type and level, and each request runs its own copy of them, so
+// that requests may be served concurrently.
+type Server struct {
+	// Token, if set, is the bearer token that requests must give in their
+	// Authorization header.
+	Token string
+
+	load LoadFunc
+
+	mu       sync.Mutex
+	controls map[controlsKey]*controlsEntry
+}
+
+type controlsKey struct {
+	nodeType check.NodeType
+	level    string
+}
+
+// controlsEntry holds the controls loaded for a controlsKey, so that they are
+// loaded once without holding Server.mu while they load.
+type controlsEntry struct {
+	once     sync.Once
+	controls *check.Controls
+	err      error
+}
+
+// New returns a Server that loads controls with load and accepts only the
+// requests carrying token. No token is required if token is empty.
+func New(load LoadFunc, token string) *Server {
+	return &Server{
+		Token:    token,
+		load:     load,
+		controls: make(map[controlsKey]*controlsEntry),
+	}
+}
+
+// ServeHTTP runs the controls selected by r and writes their results.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	t, err := check.ParseNodeType(q.Get("node"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	level := q.Get("level")
+	if level == "" {
+		level = DefaultLevel
+	}
+	n, err := strconv.ParseUint(level, 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid level %q", level), http.StatusBadRequest)
+		return
+	}
+	level = strconv.FormatUint(n, 10)
+	groups, checks := splitIDs(q.Get("group")), splitIDs(q.Get("check"))
+	if len(groups) > 0 && len(checks) > 0 {
+		http.Error(w, "group and check parameters can't be used together", http.StatusBadRequest)
+		return
+	}
+
+	controls, err := s.controlsFor(t, level)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error setting up %s controls: %v", t, err), http.StatusInternalServerError)
+		return
+	}
+
+	var result *check.Controls
+	if len(checks) > 0 {
+		result, err = controls.RunChecksResult(checks...)
+	} else {
+		result, err = controls.RunGroupResult(groups...)
+	}
+	// Errors with individual checks still leave results to report.
+	if err != nil && result.Executed() == 0 {
+		status := http.StatusInternalServerError
+		if errors.Is(err, check.ErrNoChecksMatched) || len(result.Errors) == 0 {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	out, err := result.JSON()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to output in JSON format: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// authorized reports whether r carries the bearer token of the server.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.Token == "" {
+		return true
+	}
+	h := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(h, prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(h[len(prefix):]), []byte(s.Token)) == 1
+}
+
+// controlsFor returns the controls for t and level, loading them if they
+// have not been loaded yet. Requests for other keys are not held up while
+// the controls load, and controls that fail to load are loaded again by the
+// next request. The controls returned must not be run, only copied by
+// RunGroupResult and RunChecksResult.
+func (s *Server) controlsFor(t check.NodeType, level string) (*check.Controls, error) {
+	key := controlsKey{t, level}
+
+	s.mu.Lock()
+	e, ok := s.controls[key]
+	if !ok {
+		e = &controlsEntry{}
+		s.controls[key] = e
+	}
+	s.mu.Unlock()
+
+	e.once.Do(func() { e.controls, e.err = s.load(t, level) })
+	if e.err != nil {
+		s.mu.Lock()
+		if s.controls[key] == e {
+			delete(s.controls, key)
+		}
+		s.mu.Unlock()
+		return nil, e.err
+	}
+	return e.controls, nil
+}
+
+// splitIDs splits a comma-separated list of IDs, dropping empty entries.
+func splitIDs(list string) []string {
+	var ids []string
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,182 @@
+// Copyright © 2017 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+const testControls = `---
+controls:
+id: 4
+text: "Worker Node Security Configuration"
+type: "node"
+groups:
+- id: 4.1
+  text: "Worker Node Configuration Files"
+  checks:
+  - id: 4.1.1
+    text: "Skipped check"
+    type: "skip"
+    level: 1
+  - id: 4.1.2
+    text: "Manual check"
+    type: "manual"
+    level: 1
+- id: 4.2
+  text: "Kubelet"
+  checks:
+  - id: 4.2.1
+    text: "Manual check"
+    type: "manual"
+    level: 2
+`
+
+func testServer(loads *int) *Server {
+	var mu sync.Mutex
+	return New(func(typ check.NodeType, level string) (*check.Controls, error) {
+		mu.Lock()
+		*loads++
+		mu.Unlock()
+		if typ != check.NODE {
+			return nil, errors.New("no controls file")
+		}
+		return check.NewControls(typ, level, []byte(testControls))
+	}, "secret")
+}
+
+func get(s *Server, target, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestServer_Run(t *testing.T) {
+	loads := 0
+	s := testServer(&loads)
+
+	cases := []struct {
+		target string
+		groups int
+		checks int
+		skip   int
+	}{
+		{"/?node=node", 2, 3, 0},
+		{"/?node=node&level=1", 2, 3, 1},
+		{"/?node=node&level=01", 2, 3, 1},
+		{"/?node=node&group=4.2", 1, 1, 0},
+		{"/?node=node&check=4.1.1,4.2.1", 2, 2, 0},
+	}
+	for _, c := range cases {
+		w := get(s, c.target, "secret")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", c.target, w.Code, w.Body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: unexpected content type %q", c.target, ct)
+		}
+
+		var result check.Controls
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatalf("%s: invalid JSON: %v", c.target, err)
+		}
+		n := 0
+		for _, g := range result.Groups {
+			n += len(g.Checks)
+		}
+		if len(result.Groups) != c.groups || n != c.checks {
+			t.Errorf("%s: expected %d groups and %d checks, got %d and %d", c.target, c.groups, c.checks, len(result.Groups), n)
+		}
+		if result.Skip != c.skip {
+			t.Errorf("%s: expected %d checks skipped, got %d", c.target, c.skip, result.Skip)
+		}
+	}
+
+	if loads != 2 {
+		t.Errorf("expected controls to be loaded once for each level, got %d loads", loads)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	loads := 0
+	s := testServer(&loads)
+
+	cases := []struct {
+		target string
+		token  string
+		status int
+	}{
+		{"/?node=node", "", http.StatusUnauthorized},
+		{"/?node=node", "wrong", http.StatusUnauthorized},
+		{"/?node=worker", "secret", http.StatusBadRequest},
+		{"/?node=node&group=4.1&check=4.2.1", "secret", http.StatusBadRequest},
+		{"/?node=node&check=4.1.3-4.1", "secret", http.StatusBadRequest},
+		{"/?node=master", "secret", http.StatusInternalServerError},
+		{"/?node=node&level=high", "secret", http.StatusBadRequest},
+		{"/?node=node&level=-1", "secret", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		if w := get(s, c.target, c.token); w.Code != c.status {
+			t.Errorf("%s: expected status %d, got %d: %s", c.target, c.status, w.Code, w.Body)
+		}
+	}
+
+	if loads != 2 {
+		t.Errorf("expected invalid levels to load no controls, got %d loads", loads)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/?node=node", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405 for POST, got %d", w.Code)
+	}
+}
+
+func TestServer_Concurrent(t *testing.T) {
+	loads := 0
+	s := testServer(&loads)
+
+	var wg sync.WaitGroup
+	codes := make([]int, 8)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = get(s, "/?node=node&group=4.1", "secret").Code
+		}(i)
+	}
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request %d: expected status 200, got %d", i, code)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("expected controls to be loaded once, got %d loads", loads)
+	}
+}